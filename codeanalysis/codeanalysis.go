@@ -2,7 +2,6 @@ package codeanalysis
 
 import (
 	"go/parser"
-	"path/filepath"
 	"os"
 	"strings"
 	"go/token"
@@ -13,17 +12,86 @@ import (
 	"fmt"
 	"path"
 	"encoding/json"
+	"regexp"
+	"go/build"
 )
 
 type Config struct {
-	CodeDir    string
-	GopathDir  string
-	VendorDir  string
-	IgnoreDirs []string
+	CodeDir     string
+	GopathDir   string
+	VendorDir   string
+	// Go模块缓存目录, 为空时使用GOMODCACHE环境变量或GOPATH/pkg/mod
+	ModCacheDir string
+	IgnoreDirs  []string
+	// 使用go/types对代码目录中的包进行类型检查, 通过类型信息确定类型所在的包, 类型检查失败时使用语法树推断
+	TypeCheck   bool
+	// 把类型别名画成指向目标类型的节点
+	ShowTypeAliases bool
+	// 在嵌入了其他类型的struct中, 列出提升的字段和方法, interface始终列出嵌入的interface中的方法
+	ShowPromoted    bool
+	// UML图的详细程度, full, public-api, signatures, names-only, 为空时使用full
+	Detail          string
+	// 每个class最多显示的成员数量, 0为不限制
+	MaxMembers      int
+	// 关系的来源, fields, signatures, bodies, 为nil时使用fields和signatures
+	RelationSources []string
+	// 输出格式, plantuml, mermaid, dot, svg, 为空时使用plantuml
+	Format          string
+	// 图的内容, types为类型图, packages为包的import关系图, 为空时使用types
+	View            string
+	// 包的import关系图中合并为一个节点的包, stdlib, vendor, external
+	CollapsePackages []string
+	// 查找包之间和类型之间的循环依赖
+	DetectCycles    bool
+	// 在图中用红色画出循环依赖中的边, 需要同时设置DetectCycles
+	HighlightCycles bool
+	// 需要检查的架构规则, 检查时不要设置CollapsePackages
+	Rules           []*Rule
+	// 只显示从该类型出发FocusDepth步以内的类型, 例如 github.com/a/b.User, 包路径可以是相对于代码目录的路径
+	Focus           string
+	// Focus的步数, 0时使用1
+	FocusDepth      int
+	// Focus时沿着关系的方向, out, in, both, 为空时使用both
+	FocusDirection  string
+	// 只显示匹配的包中的类型, 匹配规则和Rule相同, 例如 internal/**
+	IncludePackages []string
+	// 不显示匹配的包中的类型
+	ExcludePackages []string
+	// 只解析匹配的go文件, 没有/时匹配文件名, 有/时匹配相对于代码目录的路径
+	IncludeFiles    []string
+	// 不解析匹配的go文件, 例如 *_gen.go, mock_*.go
+	ExcludeFiles    []string
+	// 只显示类型名匹配正则表达式的类型
+	IncludeTypes    []string
+	// 不显示类型名匹配正则表达式的类型
+	ExcludeTypes    []string
+	// 不解析.gitignore中忽略的文件
+	UseGitignore    bool
+	// 排除的类型被图中的类型依赖或实现时, 做为灰色的边界类型显示
+	ShowExcludedStubs bool
+	// 生成的代码的处理方式, show, skip, separate, collapse, 为空时使用show
+	Generated       string
+	// 按照构建约束选择go文件时的操作系统和CPU架构, 为空时使用当前环境
+	GOOS            string
+	GOARCH          string
+	// 构建标签, 例如 integration, debug
+	BuildTags       []string
+	// 把类型所在文件的构建约束做为构造型显示, 例如 <<linux>>
+	ShowPlatform    bool
+	// 解析_test.go文件, 测试中的类型和外部测试包单独分组, 画出测试文件到被测试类型的关系
+	Tests           bool
 }
 
 type AnalysisResult interface {
 	OutputToFile(logfile string)
+	// 循环依赖报告, 没有设置DetectCycles或者没有循环依赖时为空
+	CycleReport() string
+	// 违反架构规则的依赖, 没有设置Rules时为空
+	RuleViolations() []*RuleViolation
+	// 每个包的耦合度和抽象度
+	Metrics() []*PackageMetrics
+	// 配置错误等导致分析没有完成时的错误, 此时其他结果为空
+	Err() error
 }
 
 func AnalysisCode(config Config) AnalysisResult {
@@ -32,7 +100,14 @@ func AnalysisCode(config Config) AnalysisResult {
 		structMetas : []*structMeta{},
 		typeAliasMetas : []*typeAliasMeta{},
 		packagePathPackageNameCache : map[string]string{},
+		packageNodes : []*packageNode{},
+		packageEdges : []*packageEdge{},
 		dependencyRelations : []*DependencyRelation{},
+		moduleCache : map[string]*moduleMeta{},
+		methodReceivers : map[string]bool{},
+		enumValues : map[string][]string{},
+		generatedFiles : map[string]bool{},
+		fileConstraints : map[string]string{},
 	}
 	tool.analysis(config)
 	return tool
@@ -60,15 +135,6 @@ func sliceContains(src []string, value string) bool {
 	return isContain
 }
 
-func sliceContainsSlice(s []string, s2 []string) bool {
-	for _, str := range s2 {
-		if ! sliceContains(s, str) {
-			return false
-		}
-	}
-	return true
-}
-
 func mapContains(src map[string]string, key string) bool {
 	if _, ok := src[key]; ok {
 		return true
@@ -140,8 +206,16 @@ type interfaceMeta struct {
 	Name        string
 	// interface的方法签名列表,
 	MethodSigns []string
-	// UML图节点
-	UML         string
+	// interface的方法
+	Methods     []*methodMeta
+	// 嵌入的interface
+	Embeds      []*embedMeta
+	// 泛型interface的类型参数
+	TypeParams  []*typeParamMeta
+	// 方法签名中依赖的类型
+	Uses        []*typeUse
+	// 类型约束interface中的类型元素, 例如 ~int | ~string
+	TypeUnion   []string
 }
 
 func (this *interfaceMeta) UniqueNameUML() string {
@@ -153,35 +227,95 @@ type structMeta struct {
 	Name        string
 	// struct的方法签名列表
 	MethodSigns []string
-	// UML图节点
-	UML         string
+	// struct的方法
+	Methods     []*methodMeta
+	// struct的字段, 包含匿名嵌入的字段
+	Fields      []*fieldMeta
+	// 匿名嵌入的字段
+	Embeds      []*embedMeta
+	// 泛型struct的类型参数
+	TypeParams  []*typeParamMeta
+	// 方法签名和方法体中依赖的类型
+	Uses        []*typeUse
+	// 非struct的命名类型的底层类型, 例如 type Status int 中的int, struct时为空
+	UnderlyingType string
+	// 枚举值, 例如 const ( StateA State = iota; StateB ) 中的StateA, StateB
+	EnumValues  []string
+	// 合并为一个节点的生成的类型名, 只有合并后的节点才有
+	CollapsedTypes []string
 }
 
 type typeAliasMeta struct {
 	baseInfo
 	Name           string
 	targetTypeName string
+	// 泛型类型的类型参数
+	TypeParams     []*typeParamMeta
+	// 是否为类型别名, 例如 type A = pkg.B
+	IsAlias        bool
+	// 类型别名指向的类型表达式
+	targetExpr     ast.Expr
+	// 类型别名指向的类型所在的包路径和类型名, 无法确定时为空
+	TargetPackagePath string
+	TargetName     string
 }
 
 func (this *structMeta) UniqueNameUML() string {
 	return packagePathToUML(this.PackagePath) + "." + this.Name
 }
 
-func (this *structMeta) implInterfaceUML(interfaceMeta1 *interfaceMeta) string {
-	return fmt.Sprintf("%s <|- %s\n", interfaceMeta1.UniqueNameUML(), this.UniqueNameUML())
-}
 
 type importMeta struct {
 	// 例如 main
 	Alias string
 	// 例如 github.com/maobuji/list-interface
 	Path  string
+	// import语句在源码中的位置
+	Position token.Position
+}
+
+// UML图中的节点, struct和interface
+type typeMeta interface {
+	UniqueNameUML() string
 }
 
+// 关系的类型
+const (
+	// 组合, 值类型的字段
+	relationComposition = "composition"
+	// 聚合, 指针和interface类型的字段
+	relationAggregation = "aggregation"
+	// 关联, chan, func类型的字段和泛型的类型实参
+	relationAssociation = "association"
+	// 依赖, 方法中使用的类型
+	relationDependency = "dependency"
+	// 泛化, interface嵌入interface
+	relationExtends = "extends"
+	// struct嵌入类型
+	relationEmbeds = "embeds"
+)
+
 type DependencyRelation struct {
-	source *structMeta
-	target *structMeta
-	uml    string
+	source typeMeta
+	target typeMeta
+	// 关系的类型, 例如 composition
+	kind         string
+	// 目标端的多重性, 例如 切片为*, 数组为长度
+	multiplicity string
+	// map字段的key类型
+	qualifier    string
+	// 字段名
+	label        string
+	// 构造型, 例如 chan, func
+	stereotype   string
+	// 以*T的方式嵌入
+	pointer      bool
+	// 形成关系的源码位置, 例如字段的类型, 方法参数的类型
+	position     token.Position
+	// 方法中使用的类型所在的方法名
+	method       string
+	// 是否在循环依赖中
+	cycle        bool
 }
 
 type analysisTool struct {
@@ -193,6 +327,14 @@ type analysisTool struct {
 	currentPackagePath          string
 	// 当前解析的go文件,引入的其他包
 	currentFileImports          []*importMeta
+	// 当前解析的go文件所属的FileSet
+	currentFset                 *token.FileSet
+	// 当前解析的泛型类型或方法的类型参数名
+	currentTypeParams           []string
+	// 有方法的类型, key为 包路径.类型名
+	methodReceivers             map[string]bool
+	// const块中定义的枚举值, key为 包路径.类型名
+	enumValues                  map[string][]string
 
 	// 所有的interface
 	interfaceMetas              []*interfaceMeta
@@ -204,6 +346,39 @@ type analysisTool struct {
 	packagePathPackageNameCache map[string]string
 	// struct之间的依赖关系
 	dependencyRelations         []*DependencyRelation
+	// 目录与所属go.mod模块的映射关系, 不属于任何模块时为nil
+	moduleCache                 map[string]*moduleMeta
+	// 类型检查结果, 未开启类型检查时为nil
+	typeChecker                 *typeChecker
+	// 包的import关系图中的节点, 只有View为packages时才收集
+	packageNodes                []*packageNode
+	// 包的import关系图中的边
+	packageEdges                []*packageEdge
+	// 包之间和类型之间的循环依赖, 只有DetectCycles时才查找
+	cycles                      []*dependencyCycle
+	// 违反架构规则的依赖
+	ruleViolations              []*RuleViolation
+	// 包级别的函数中使用的类型
+	funcUses                    []*funcUsesMeta
+	// Focus范围内的类型和到Focus类型的步数, 没有设置Focus时为nil
+	focusTypes                  map[typeMeta]int
+	// IncludeTypes和ExcludeTypes编译后的正则表达式
+	includeTypes                []*regexp.Regexp
+	excludeTypes                []*regexp.Regexp
+	// 代码目录中的.gitignore, 没有设置UseGitignore时为nil
+	gitignore                   *gitignore
+	// 做为边界类型显示的排除类型
+	excludedStubs               map[typeMeta]bool
+	// go文件是否为生成的代码, key为文件路径
+	generatedFiles              map[string]bool
+	// 选择go文件的构建环境
+	buildContext                build.Context
+	// go文件的构建约束, key为文件路径
+	fileConstraints             map[string]string
+	// 有测试函数的测试文件, 只有Tests时才收集
+	testFiles                   []*testFileMeta
+	// 分析没有完成时的错误
+	err                         error
 }
 
 func (this *analysisTool)analysis(config Config) {
@@ -211,51 +386,157 @@ func (this *analysisTool)analysis(config Config) {
 	this.config = config
 
 	if this.config.CodeDir == "" || ! PathExists(this.config.CodeDir) {
-		log.Errorf("找不到代码目录%s\n", this.config.CodeDir)
+		this.fail("找不到代码目录%s\n", this.config.CodeDir)
+		return
+	}
+
+	// 使用go.mod时GOPATH目录可以为空
+	if this.config.GopathDir != "" && ! PathExists(this.config.GopathDir) {
+		this.fail("找不到GOPATH目录%s\n", this.config.GopathDir)
+		return
+	}
+
+	if this.config.GopathDir == "" && this.findModule(this.config.CodeDir) == nil {
+		this.fail("代码目录%s不在任何go.mod模块中, 也没有设置GOPATH目录\n", this.config.CodeDir)
+		return
+	}
+
+	if this.config.Detail == "" {
+		this.config.Detail = DetailFull
+	}
+
+	if !sliceContains(details, this.config.Detail) {
+		this.fail("不支持的详细程度%s, 可选值为%s\n", this.config.Detail, strings.Join(details, ", "))
+		return
+	}
+
+	if this.config.Format == "" {
+		this.config.Format = FormatPlantUML
+	}
+
+	if !sliceContains(formats, this.config.Format) {
+		this.fail("不支持的输出格式%s, 可选值为%s\n", this.config.Format, strings.Join(formats, ", "))
+		return
+	}
+
+	if this.config.View == "" {
+		this.config.View = ViewTypes
+	}
+
+	if !sliceContains(views, this.config.View) {
+		this.fail("不支持的视图%s, 可选值为%s\n", this.config.View, strings.Join(views, ", "))
+		return
+	}
+
+	for _, kind := range this.config.CollapsePackages {
+		if !sliceContains(collapsiblePackages, kind) {
+			this.fail("不支持合并的包%s, 可选值为%s\n", kind, strings.Join(collapsiblePackages, ", "))
+			return
+		}
+	}
+
+	if this.config.FocusDepth <= 0 {
+		this.config.FocusDepth = 1
+	}
+
+	if this.config.FocusDirection == "" {
+		this.config.FocusDirection = FocusBoth
+	}
+
+	if !sliceContains(focusDirections, this.config.FocusDirection) {
+		this.fail("不支持的方向%s, 可选值为%s\n", this.config.FocusDirection, strings.Join(focusDirections, ", "))
+		return
+	}
+
+	if this.config.Generated == "" {
+		this.config.Generated = GeneratedShow
+	}
+
+	if !sliceContains(generatedModes, this.config.Generated) {
+		this.fail("不支持的生成代码处理方式%s, 可选值为%s\n", this.config.Generated, strings.Join(generatedModes, ", "))
 		return
 	}
 
-	if this.config.GopathDir == "" || ! PathExists(this.config.GopathDir) {
-		log.Errorf("找不到GOPATH目录%s\n", this.config.GopathDir)
+	this.buildContext = newBuildContext(this.config)
+
+	if err := this.compileFilters(); err != nil {
+		this.fail("%s\n", err)
 		return
 	}
 
+	if this.config.RelationSources == nil {
+		this.config.RelationSources = []string{RelationFields, RelationSignatures}
+	}
+
+	for _, source := range this.config.RelationSources {
+		if !sliceContains(relationSources, source) {
+			this.fail("不支持的关系来源%s, 可选值为%s\n", source, strings.Join(relationSources, ", "))
+			return
+		}
+	}
+
+	if this.config.ModCacheDir == "" {
+		this.config.ModCacheDir = defaultModCacheDir(this.config.GopathDir)
+	}
+
 	for _, lib := range stdlibs {
 		this.mapPackagePath_PackageName(lib, path.Base(lib))
 	}
 
-	dir_walk_once := func(path string, info os.FileInfo, err error) error {
-		// 过滤掉测试代码
-		if strings.HasSuffix(path, ".go") && ! strings.HasSuffix(path, "test.go") {
-			if config.IgnoreDirs != nil && HasPrefixInSomeElement(path, config.IgnoreDirs) {
-				// ignore
-			} else {
-				log.Info("解析 " + path)
-				this.visitTypeInFile(path)
-			}
-		}
+	if this.config.TypeCheck {
+		this.typeChecker = newTypeChecker(this)
+		this.typeChecker.checkCodeDir()
+	}
 
-		return nil
+	this.walkGoFiles(this.visitTypeInFile)
+
+	this.promoteNamedTypes()
+
+	this.resolveTypeAliases()
+
+	this.walkGoFiles(this.visitFuncInFile)
+
+	this.visitUses()
+
+	if this.config.Tests {
+		this.visitTestRelations()
 	}
 
-	filepath.Walk(config.CodeDir, dir_walk_once)
+	if this.config.Generated == GeneratedCollapse {
+		this.collapseGenerated()
+	}
 
-	dir_walk_twice := func(path string, info os.FileInfo, err error) error {
-		// 过滤掉测试代码
-		if strings.HasSuffix(path, ".go") && ! strings.HasSuffix(path, "test.go") {
-			if config.IgnoreDirs != nil && HasPrefixInSomeElement(path, config.IgnoreDirs) {
-				// ignore
-			} else {
-				log.Info("解析 " + path)
-				this.visitFuncInFile(path)
-			}
+	if this.config.DetectCycles {
+		this.detectCycles()
+	}
+
+	if len(this.config.Rules) > 0 {
+		this.checkRules()
+	}
+
+	if this.config.Focus != "" {
+		this.computeFocus()
+		if this.err != nil {
+			return
 		}
+	}
 
-		return nil
+	if this.config.ShowExcludedStubs {
+		this.computeExcludedStubs()
 	}
 
-	filepath.Walk(config.CodeDir, dir_walk_twice)
+}
+
+/**
+ * 记录导致分析无法继续的错误, 调用者需要在之后返回
+ */
+func (this *analysisTool) fail(format string, args ...interface{}) {
+	this.err = fmt.Errorf(strings.TrimSuffix(format, "\n"), args...)
+	log.Error(this.err)
+}
 
+func (this *analysisTool) Err() error {
+	return this.err
 }
 
 func (this *analysisTool) initFile(path string) {
@@ -286,18 +567,35 @@ func (this *analysisTool) mapPackagePath_PackageName(packagePath string, package
 
 }
 
+/**
+ * 解析go文件, 类型检查模式下直接使用类型检查时的语法树, 以便通过语法树节点查找types.Object
+ */
+func (this *analysisTool) parseFile(path string) (*ast.File, error) {
+
+	if this.typeChecker != nil {
+		if file := this.typeChecker.files[path]; file != nil {
+			this.currentFset = this.typeChecker.fset
+			return file, nil
+		}
+	}
+
+	this.currentFset = token.NewFileSet()
+	return parser.ParseFile(this.currentFset, path, nil, parser.ParseComments)
+}
+
 func (this *analysisTool) visitTypeInFile(path string) {
 
 	this.initFile(path)
 
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	file, err := this.parseFile(path)
 
 	if err != nil {
 		log.Fatal(err)
 		return
 	}
 
+	this.initTestPackage(file)
+
 	this.mapPackagePath_PackageName(this.currentPackagePath, file.Name.Name)
 
 	for _, decl := range file.Decls {
@@ -313,23 +611,87 @@ func (this *analysisTool) visitTypeInFile(path string) {
 					this.visitTypeSpec(typeSpec)
 				}
 			}
+
+			if genDecl.Tok == token.CONST && genDecl.Lparen.IsValid() {
+				this.visitConstBlock(genDecl)
+			}
+		}
+
+		// 记录有方法的类型, 非struct的命名类型有方法时也做为类画出来
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if ok {
+			_, structName, _ := this.findStructTypeOfFunc(funcDecl)
+			if structName != "" {
+				this.methodReceivers[this.currentPackagePath + "." + structName] = true
+			}
 		}
 
 	}
 
 }
 
+/**
+ * 从const块中收集枚举值, 例如
+ * const (
+ *     StateA State = iota
+ *     StateB
+ * )
+ * 没有类型和值的常量沿用上一个常量的类型
+ */
+func (this *analysisTool) visitConstBlock(genDecl *ast.GenDecl) {
+
+	typeName := ""
+
+	for _, spec := range genDecl.Specs {
+
+		valueSpec, ok := spec.(*ast.ValueSpec)
+		if !ok {
+			continue
+		}
+
+		if valueSpec.Type != nil {
+			typeName = ""
+			ident, ok := valueSpec.Type.(*ast.Ident)
+			if ok {
+				typeName = ident.Name
+			}
+		} else if len(valueSpec.Values) > 0 {
+			// 类型转换的写法, 例如 StateA = State(iota)
+			typeName = ""
+			callExpr, ok := valueSpec.Values[0].(*ast.CallExpr)
+			if ok && len(callExpr.Args) == 1 {
+				ident, ok := callExpr.Fun.(*ast.Ident)
+				if ok {
+					typeName = ident.Name
+				}
+			}
+		}
+
+		if typeName == "" || this.isGoBaseType(typeName) {
+			continue
+		}
+
+		key := this.currentPackagePath + "." + typeName
+		for _, name := range valueSpec.Names {
+			if name.Name != "_" {
+				this.enumValues[key] = append(this.enumValues[key], name.Name)
+			}
+		}
+	}
+
+}
+
 func (this *analysisTool) visitTypeSpec(typeSpec *ast.TypeSpec) {
 
 	interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
 	if ok {
-		this.visitInterfaceType(typeSpec.Name.Name, interfaceType)
+		this.visitInterfaceType(typeSpec.Name.Name, typeSpec.TypeParams, interfaceType)
 		return
 	}
 
 	structType, ok := typeSpec.Type.(*ast.StructType)
 	if ok {
-		this.visitStructType(typeSpec.Name.Name, structType)
+		this.visitStructType(typeSpec.Name.Name, typeSpec.TypeParams, structType)
 		return
 	}
 
@@ -340,14 +702,19 @@ func (this *analysisTool) visitTypeSpec(typeSpec *ast.TypeSpec) {
 			PackagePath : this.currentPackagePath,
 		},
 		Name : typeSpec.Name.Name,
-		targetTypeName: "",
+		targetTypeName: this.typeToString(typeSpec.Type, false),
+		TypeParams: this.createTypeParamMetas(typeSpec.TypeParams),
+		IsAlias: typeSpec.Assign.IsValid(),
+		targetExpr: typeSpec.Type,
 	})
 
 }
 
 func (this*analysisTool) filepathToPackagePath(filepath string) string {
+	return this.dirToPackagePath(path.Dir(filepath))
+}
 
-	filepath = path.Dir(filepath)
+func (this*analysisTool) dirToPackagePath(filepath string) string {
 
 	if this.config.VendorDir != "" {
 		if (strings.HasPrefix(filepath, this.config.VendorDir)) {
@@ -357,6 +724,12 @@ func (this*analysisTool) filepathToPackagePath(filepath string) string {
 		}
 	}
 
+	module := this.findModule(filepath)
+	if module != nil {
+		subPath := strings.TrimPrefix(strings.TrimPrefix(filepath, module.Dir), "/")
+		return path.Join(module.Path, subPath)
+	}
+
 	if this.config.GopathDir != "" {
 		srcdir := path.Join(this.config.GopathDir, "src")
 		if strings.HasPrefix(filepath, srcdir) {
@@ -372,19 +745,12 @@ func (this*analysisTool) filepathToPackagePath(filepath string) string {
 
 }
 
-func (this *analysisTool) visitFuncInFile(path string) {
-
-	this.initFile(path)
-
-	fset := token.NewFileSet()
-	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
-
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
+/**
+ * 解析go文件引入的包, 没有别名时使用包名做为别名
+ */
+func (this *analysisTool) parseImports(file *ast.File) []*importMeta {
 
-	this.currentFileImports = []*importMeta{}
+	imports := []*importMeta{}
 
 	if file.Imports != nil {
 		for _, import1 := range file.Imports {
@@ -406,13 +772,37 @@ func (this *analysisTool) visitFuncInFile(path string) {
 
 			log.Debugf("current_file=%s packagePath=%s, alias=%s\n", this.currentFile, packagePath, alias)
 
-			this.currentFileImports = append(this.currentFileImports, &importMeta{
+			imports = append(imports, &importMeta{
 				Alias : alias,
 				Path:packagePath,
+				Position: this.currentFset.Position(import1.Pos()),
 			})
 		}
 	}
 
+	return imports
+}
+
+func (this *analysisTool) visitFuncInFile(path string) {
+
+	this.initFile(path)
+
+	file, err := this.parseFile(path)
+
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	this.initTestPackage(file)
+
+	this.currentFileImports = this.parseImports(file)
+
+	// 包的import关系只按照非测试代码计算
+	if (this.config.View == ViewPackages || this.config.DetectCycles || len(this.config.Rules) > 0) && !isTestFile(path) {
+		this.visitPackageImports(this.currentFileImports)
+	}
+
 	for _, decl := range file.Decls {
 
 		genDecl, ok := decl.(*ast.GenDecl)
@@ -424,6 +814,8 @@ func (this *analysisTool) visitFuncInFile(path string) {
 
 				if ok {
 
+					this.currentTypeParams = typeParamNames(this.createTypeParamMetas(typeSpec.TypeParams))
+
 					interfaceType, ok := typeSpec.Type.(*ast.InterfaceType)
 					if ok {
 						this.visitInterfaceFunctions(typeSpec.Name.Name, interfaceType)
@@ -434,6 +826,11 @@ func (this *analysisTool) visitFuncInFile(path string) {
 						this.visitStructFields(typeSpec.Name.Name, structType)
 					}
 
+					if !ok && this.findStruct(this.currentPackagePath, typeSpec.Name.Name) != nil {
+						this.visitNamedType(typeSpec.Name.Name, typeSpec.Type)
+					}
+
+					this.currentTypeParams = nil
 				}
 			}
 		}
@@ -445,13 +842,59 @@ func (this *analysisTool) visitFuncInFile(path string) {
 		funcDecl, ok := decl.(*ast.FuncDecl)
 		if ok {
 			this.visitFunc(funcDecl)
+			this.visitFuncUses(funcDecl)
+		}
+
+	}
+
+	if isTestFile(path) {
+		this.visitTestFuncs(file)
+	}
+
+}
+
+/**
+ * 有方法或者枚举值的非struct命名类型, 例如 type Status int, type Handlers []Handler, 转换为structMeta, 和struct一样画出来
+ */
+func (this *analysisTool) promoteNamedTypes() {
+
+	typeAliasMetas := []*typeAliasMeta{}
+
+	for _, typeAliasMeta1 := range this.typeAliasMetas {
+
+		key := typeAliasMeta1.PackagePath + "." + typeAliasMeta1.Name
+
+		if typeAliasMeta1.IsAlias || !this.methodReceivers[key] && len(this.enumValues[key]) == 0 {
+			typeAliasMetas = append(typeAliasMetas, typeAliasMeta1)
+			continue
 		}
 
+		this.structMetas = append(this.structMetas, &structMeta{
+			baseInfo : typeAliasMeta1.baseInfo,
+			Name : typeAliasMeta1.Name,
+			MethodSigns:[]string{},
+			TypeParams: typeAliasMeta1.TypeParams,
+			UnderlyingType: typeAliasMeta1.targetTypeName,
+			EnumValues: this.enumValues[key],
+		})
 	}
 
+	this.typeAliasMetas = typeAliasMetas
+
 }
 
-func (this *analysisTool) visitStructType(name string, structType *ast.StructType) {
+func (this *analysisTool) visitNamedType(name string, underlyingType ast.Expr) {
+
+	sourceStruct1 := this.findStruct(this.currentPackagePath, name)
+
+	// 底层类型引用的类型, 例如 type Handlers []Handler 依赖Handler
+	if this.hasRelationSource(RelationFields) {
+		this.visitFieldRelations(sourceStruct1, underlyingType, "")
+	}
+
+}
+
+func (this *analysisTool) visitStructType(name string, typeParams *ast.FieldList, structType *ast.StructType) {
 
 	strutMeta1 := &structMeta{
 		baseInfo : baseInfo{
@@ -460,6 +903,7 @@ func (this *analysisTool) visitStructType(name string, structType *ast.StructTyp
 		},
 		Name : name,
 		MethodSigns:[]string{},
+		TypeParams: this.createTypeParamMetas(typeParams),
 	}
 
 	this.structMetas = append(this.structMetas, strutMeta1)
@@ -470,8 +914,6 @@ func (this *analysisTool) visitStructFields(structName string, structType *ast.S
 
 	sourceStruct1 := this.findStruct(this.currentPackagePath, structName)
 
-	sourceStruct1.UML = this.structToUML(structName, structType)
-
 	for _, field := range structType.Fields.List {
 		this.visitStructField(sourceStruct1, field)
 	}
@@ -482,53 +924,44 @@ func (this *analysisTool) visitStructField(sourceStruct1 *structMeta, field *ast
 
 	fieldNames := this.IdentsToString(field.Names)
 
-	targetStruct1, isarray := this.analysisTypeForDependencyRelation(field.Type)
-
-	if targetStruct1 != nil {
-
-		if fieldNames == "" {
-
-			d := DependencyRelation{
-				source: sourceStruct1,
-				target:targetStruct1,
-				uml : sourceStruct1.UniqueNameUML() + " ..|> " + targetStruct1.UniqueNameUML(),
-			}
-
-			this.dependencyRelations = append(this.dependencyRelations, &d)
-
-		} else {
-
-			if isarray {
-
-				d := DependencyRelation{
-					source: sourceStruct1,
-					target:targetStruct1,
-					uml : sourceStruct1.UniqueNameUML() + " ---> \"*\" " + targetStruct1.UniqueNameUML() + " : " + fieldNames,
-				}
-
-				this.dependencyRelations = append(this.dependencyRelations, &d)
-
-			} else {
-				d := DependencyRelation{
-					source: sourceStruct1,
-					target:targetStruct1,
-					uml : sourceStruct1.UniqueNameUML() + " ---> " + targetStruct1.UniqueNameUML() + " : " + fieldNames,
-				}
+	for _, name := range field.Names {
+		sourceStruct1.Fields = append(sourceStruct1.Fields, &fieldMeta{
+			Name: name.Name,
+			Type: this.typeToString(field.Type, false),
+		})
+	}
+
+	if fieldNames == "" {
+		embedMeta1 := this.createEmbedMeta(field.Type)
+		sourceStruct1.Fields = append(sourceStruct1.Fields, &fieldMeta{
+			Name:     embedMeta1.Name,
+			Type:     this.typeToString(field.Type, false),
+			Embedded: true,
+		})
+		sourceStruct1.Embeds = append(sourceStruct1.Embeds, embedMeta1)
+		this.visitEmbed(sourceStruct1, embedMeta1, this.currentFset.Position(field.Type.Pos()))
+		return
+	}
 
-				this.dependencyRelations = append(this.dependencyRelations, &d)
+	if this.hasRelationSource(RelationFields) {
+		this.visitFieldRelations(sourceStruct1, field.Type, fieldNames)
+	}
 
-			}
+}
 
+func typeMetaSliceContains(src []typeMeta, value typeMeta) bool {
+	for _, srcValue := range src {
+		if srcValue == value {
+			return true
 		}
-
 	}
-
+	return false
 }
 
 func (this *analysisTool) isGoBaseType(type1 string) bool {
 
 	baseTypes := []string{"bool", "byte", "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
-		"float32", "float64", "complex64", "complex128", "string", "uintptr", "rune", "error"}
+		"float32", "float64", "complex64", "complex128", "string", "uintptr", "rune", "error", "any", "comparable"}
 
 	if sliceContains(baseTypes, type1) {
 		return true
@@ -537,59 +970,110 @@ func (this *analysisTool) isGoBaseType(type1 string) bool {
 	return false
 }
 
-func (this *analysisTool) findStructByAliasAndStructName(alias string, structName string) (*structMeta) {
+func (this *analysisTool) findTypeByAliasAndTypeName(alias string, typeName string) typeMeta {
 
-	if alias == "" && this.isGoBaseType(structName) {
+	if alias == "" && this.isGoBaseType(typeName) {
 		return nil
 	}
 
-	packagepath := this.findPackagePathByAlias(alias, structName)
+	packagepath := this.findPackagePathByAlias(alias, typeName)
 
 	if packagepath != "" {
-		return this.findStruct(packagepath, structName)
+		packagepath, typeName = this.followTypeAlias(packagepath, typeName)
+		return this.findTypeMeta(packagepath, typeName)
+	}
+
+	return nil
+}
+
+/**
+ * 类型检查模式下直接使用类型信息查找类型, 否则根据包别名推断
+ */
+func (this *analysisTool) findTypeByTypeExpr(t ast.Expr, alias string, typeName string) typeMeta {
+
+	if packagePath, name, ok := this.resolveTypeExpr(t); ok {
+		if packagePath == "" {
+			return nil
+		}
+		packagePath, name = this.followTypeAlias(packagePath, name)
+		return this.findTypeMeta(packagePath, name)
+	}
+
+	return this.findTypeByAliasAndTypeName(alias, typeName)
+}
+
+/**
+ * 查找UML图中的节点, 包括struct, 有方法的命名类型, 枚举和interface
+ */
+func (this *analysisTool) findTypeMeta(packagePath string, typeName string) typeMeta {
+
+	if structMeta1 := this.findStruct(packagePath, typeName); structMeta1 != nil {
+		return structMeta1
+	}
+
+	if interfaceMeta1 := this.findInterfaceMeta(packagePath, typeName); interfaceMeta1 != nil {
+		return interfaceMeta1
 	}
 
 	return nil
 }
 
-func (this *analysisTool) analysisTypeForDependencyRelation(t ast.Expr) (structMeta1 *structMeta, isArray bool) {
+/**
+ * 字段类型依赖的节点, 切片, 数组和map的isArray为true
+ */
+func (this *analysisTool) analysisTypeForDependencyRelation(t ast.Expr) (typeMeta1 typeMeta, isArray bool) {
 
-	structMeta1 = nil
+	typeMeta1 = nil
 	isArray = false
 
 	ident, ok := t.(*ast.Ident)
 	if ok {
-		structMeta1 = this.findStructByAliasAndStructName("", ident.Name)
+		if !this.isTypeParam(ident.Name) {
+			typeMeta1 = this.findTypeByTypeExpr(ident, "", ident.Name)
+		}
 		isArray = false
 		return
 	}
 
+	// 泛型实例化, 例如 Box[User], 依赖泛型类型本身
+	if genericBaseType(t) != t {
+		typeMeta1, isArray = this.analysisTypeForDependencyRelation(genericBaseType(t))
+		return
+	}
+
 	starExpr, ok := t.(*ast.StarExpr)
 	if ok {
-		structMeta1, isArray = this.analysisTypeForDependencyRelation(starExpr.X)
+		typeMeta1, isArray = this.analysisTypeForDependencyRelation(starExpr.X)
 		return
 	}
 
 	arrayType, ok := t.(*ast.ArrayType)
 	if ok {
-		eleStructName, _ := this.analysisTypeForDependencyRelation(arrayType.Elt)
-		structMeta1 = eleStructName
+		eleTypeMeta1, _ := this.analysisTypeForDependencyRelation(arrayType.Elt)
+		typeMeta1 = eleTypeMeta1
 		isArray = true
 		return
 	}
 
 	mapType, ok := t.(*ast.MapType)
 	if ok {
-		valueStructMeta1, _ := this.analysisTypeForDependencyRelation(mapType.Value)
-		structMeta1 = valueStructMeta1
+		valueTypeMeta1, _ := this.analysisTypeForDependencyRelation(mapType.Value)
+		typeMeta1 = valueTypeMeta1
 		isArray = true
 		return
 	}
 
+	chanType, ok := t.(*ast.ChanType)
+	if ok {
+		typeMeta1, _ = this.analysisTypeForDependencyRelation(chanType.Value)
+		isArray = false
+		return
+	}
+
 	selectorExpr, ok := t.(*ast.SelectorExpr)
 	if ok {
 		alias := this.typeToString(selectorExpr.X, false)
-		structMeta1 = this.findStructByAliasAndStructName(alias, this.typeToString(selectorExpr.Sel, false))
+		typeMeta1 = this.findTypeByTypeExpr(selectorExpr, alias, this.typeToString(selectorExpr.Sel, false))
 		isArray = false
 		return
 	}
@@ -597,30 +1081,11 @@ func (this *analysisTool) analysisTypeForDependencyRelation(t ast.Expr) (structM
 	return
 }
 
-func (this *analysisTool) structToUML(name string, structType *ast.StructType) string {
-	classUML := "class " + name + " " + this.structBodyToString(structType)
-	return fmt.Sprintf("namespace %s {\n %s \n}", this.packagePathToUML(this.currentPackagePath), classUML)
-}
-
 func (this *analysisTool) packagePathToUML(packagePath string) string {
 	return packagePathToUML(packagePath)
 }
 
-func (this *analysisTool) structBodyToString(structType *ast.StructType) string {
-
-	result := "{\n"
-
-	for _, field := range structType.Fields.List {
-		result += "  " + this.fieldToString(field) + "\n"
-	}
-
-	result += "}"
-
-	return result
-
-}
-
-func (this *analysisTool) visitInterfaceType(name string, interfaceType *ast.InterfaceType) {
+func (this *analysisTool) visitInterfaceType(name string, typeParams *ast.FieldList, interfaceType *ast.InterfaceType) {
 
 	interfaceInfo1 := &interfaceMeta{
 		baseInfo : baseInfo{
@@ -628,17 +1093,13 @@ func (this *analysisTool) visitInterfaceType(name string, interfaceType *ast.Int
 			PackagePath : this.currentPackagePath,
 		},
 		Name:name,
+		TypeParams: this.createTypeParamMetas(typeParams),
 	}
 
 	this.interfaceMetas = append(this.interfaceMetas, interfaceInfo1)
 
 }
 
-func (this *analysisTool) interfaceToUML(name string, interfaceType *ast.InterfaceType) string {
-	interfaceUML := "interface " + name + " " + this.interfaceBodyToString(interfaceType)
-	return fmt.Sprintf("namespace %s {\n %s \n}", this.packagePathToUML(this.currentPackagePath), interfaceUML)
-}
-
 func (this *analysisTool) funcParamsResultsToString(funcType *ast.FuncType) string {
 
 	funcString := "("
@@ -678,6 +1139,60 @@ func (this *analysisTool) funcParamsResultsToString(funcType *ast.FuncType) stri
 
 }
 
+/**
+ * 方法的参数和返回值, 例如 Read(p []byte) (n int, err error) 的参数为 p []byte, 返回值为 (n int, err error)
+ * 只有一个没有名字的返回值时不加括号
+ */
+func (this *analysisTool) methodSignature(funcType *ast.FuncType) (params string, results string) {
+
+	paramList := []string{}
+	if funcType.Params != nil {
+		for _, field := range funcType.Params.List {
+			paramList = append(paramList, this.paramToUML(field))
+		}
+	}
+
+	resultList := []string{}
+	named := false
+	if funcType.Results != nil {
+		for _, field := range funcType.Results.List {
+			resultList = append(resultList, this.paramToUML(field))
+			named = named || len(field.Names) > 0
+		}
+	}
+
+	params = strings.Join(paramList, ", ")
+
+	if len(resultList) == 1 && !named {
+		results = resultList[0]
+	} else if len(resultList) > 0 {
+		results = "(" + strings.Join(resultList, ", ") + ")"
+	}
+
+	return
+}
+
+/**
+ * 方法的参数或返回值, 例如 a, b int; 可变参数显示为 opts ...Option
+ */
+func (this *analysisTool) paramToUML(f *ast.Field) string {
+
+	paramType := ""
+
+	ellipsis, ok := f.Type.(*ast.Ellipsis)
+	if ok {
+		paramType = "..." + this.typeToString(ellipsis.Elt, false)
+	} else {
+		paramType = this.typeToString(f.Type, false)
+	}
+
+	if len(f.Names) == 0 {
+		return paramType
+	}
+
+	return strings.Replace(this.IdentsToString(f.Names), ",", ", ", -1) + " " + paramType
+}
+
 func (this*analysisTool) findStruct(packagePath string, structName string) *structMeta {
 
 	for _, structMeta1 := range this.structMetas {
@@ -715,19 +1230,47 @@ func (this *analysisTool) visitFunc(funcDecl *ast.FuncDecl) {
 
 	this.debugFunc(funcDecl)
 
-	packageAlias, structName := this.findStructTypeOfFunc(funcDecl)
+	packageAlias, structName, pointerReceiver := this.findStructTypeOfFunc(funcDecl)
 
 	if structName != "" {
 
+		this.currentTypeParams = receiverTypeParamNames(funcDecl.Recv.List[0].Type)
+		defer func() {
+			this.currentTypeParams = nil
+		}()
+
 		packagePath := ""
 		if packageAlias == "" {
 			packagePath = this.currentPackagePath
 		}
 
+		// 别名类型的方法属于指向的类型
+		packagePath, structName = this.followTypeAlias(packagePath, structName)
+
 		structMeta := this.findStruct(packagePath, structName)
+		// 测试文件中给非测试类型定义的方法只在测试时存在, 不显示
+		if structMeta != nil && isTestFile(this.currentFile) && !this.isTestType(structMeta) {
+			structMeta = nil
+		}
 		if structMeta != nil {
 			methodSign := this.createMethodSign(funcDecl.Name.Name, funcDecl.Type)
+			params, results := this.methodSignature(funcDecl.Type)
 			structMeta.MethodSigns = append(structMeta.MethodSigns, methodSign)
+			structMeta.Methods = append(structMeta.Methods, &methodMeta{
+				Name:            funcDecl.Name.Name,
+				Sign:            methodSign,
+				PointerReceiver: pointerReceiver,
+				Params:          params,
+				Results:         results,
+			})
+
+			if this.hasRelationSource(RelationSignatures) {
+				this.visitSignatureUses(&structMeta.Uses, funcDecl.Name.Name, funcDecl.Type)
+			}
+
+			if this.hasRelationSource(RelationBodies) {
+				this.visitBodyUses(&structMeta.Uses, funcDecl.Name.Name, funcDecl.Body)
+			}
 		}
 	}
 
@@ -735,6 +1278,8 @@ func (this *analysisTool) visitFunc(funcDecl *ast.FuncDecl) {
 
 func (this *analysisTool) visitInterfaceFunctions(name string, interfaceType *ast.InterfaceType) {
 
+	interfaceMeta := this.findInterfaceMeta(this.currentPackagePath, name)
+
 	methods := []string{}
 
 	for _, field := range interfaceType.Methods.List {
@@ -742,24 +1287,40 @@ func (this *analysisTool) visitInterfaceFunctions(name string, interfaceType *as
 		funcType, ok := field.Type.(*ast.FuncType)
 
 		if ok {
-			methods = append(methods, this.createMethodSign(field.Names[0].Name, funcType))
+			methodSign := this.createMethodSign(field.Names[0].Name, funcType)
+			params, results := this.methodSignature(funcType)
+			methods = append(methods, methodSign)
+			interfaceMeta.Methods = append(interfaceMeta.Methods, &methodMeta{
+				Name:    field.Names[0].Name,
+				Sign:    methodSign,
+				Params:  params,
+				Results: results,
+			})
+
+			if this.hasRelationSource(RelationSignatures) {
+				this.visitSignatureUses(&interfaceMeta.Uses, field.Names[0].Name, funcType)
+			}
+		} else if this.isTypeConstraintElement(field.Type) {
+			interfaceMeta.TypeUnion = append(interfaceMeta.TypeUnion, this.typeToString(field.Type, false))
+		} else {
+			// 嵌入的interface
+			embedMeta1 := this.createEmbedMeta(field.Type)
+			interfaceMeta.Embeds = append(interfaceMeta.Embeds, embedMeta1)
+			this.visitEmbed(interfaceMeta, embedMeta1, this.currentFset.Position(field.Type.Pos()))
 		}
 	}
 
-	interfaceMeta := this.findInterfaceMeta(this.currentPackagePath, name)
 	interfaceMeta.MethodSigns = methods
 
-	interfaceMeta.UML = this.interfaceToUML(name, interfaceType)
-
 }
 
-func (this *analysisTool) findStructTypeOfFunc(funcDecl *ast.FuncDecl) (packageAlias string, structName string) {
+func (this *analysisTool) findStructTypeOfFunc(funcDecl *ast.FuncDecl) (packageAlias string, structName string, pointerReceiver bool) {
 
 	if funcDecl.Recv != nil {
 
 		for _, field := range funcDecl.Recv.List {
 
-			t := field.Type
+			t := genericBaseType(field.Type)
 
 			ident, ok := t.(*ast.Ident)
 			if ok {
@@ -769,10 +1330,11 @@ func (this *analysisTool) findStructTypeOfFunc(funcDecl *ast.FuncDecl) (packageA
 
 			starExpr, ok := t.(*ast.StarExpr)
 			if ok {
-				ident, ok := starExpr.X.(*ast.Ident)
+				ident, ok := genericBaseType(starExpr.X).(*ast.Ident)
 				if ok {
 					packageAlias = ""
 					structName = ident.Name
+					pointerReceiver = true
 				}
 
 			}
@@ -902,6 +1464,16 @@ func (this *analysisTool) fieldToString(f *ast.Field) string {
 
 func (this *analysisTool) typeToString(t ast.Expr, convertTypeToUnqiueType bool) (string) {
 
+	if convertTypeToUnqiueType {
+		if packagePath, name, ok := this.resolveTypeExpr(t); ok {
+			if packagePath == "" {
+				return name
+			}
+			packagePath, name = this.followTypeAlias(packagePath, name)
+			return packagePath + "." + name
+		}
+	}
+
 	ident, ok := t.(*ast.Ident)
 	if ok {
 		if convertTypeToUnqiueType {
@@ -938,21 +1510,22 @@ func (this *analysisTool) typeToString(t ast.Expr, convertTypeToUnqiueType bool)
 
 	interfaceType, ok := t.(*ast.InterfaceType)
 	if ok {
-		return "interface " + strings.Replace(this.interfaceBodyToString(interfaceType), "\n", " ", -1)
+		return "interface " + strings.Replace(this.anonymousInterfaceBodyToString(interfaceType), "\n", " ", -1)
 	}
 
 	selectorExpr, ok := t.(*ast.SelectorExpr)
 	if ok {
 		if convertTypeToUnqiueType {
-			return this.findPackagePathByAlias(this.selectorExprToString(selectorExpr.X), selectorExpr.Sel.Name) + "." + selectorExpr.Sel.Name
+			packagePath, name := this.followTypeAlias(this.findPackagePathByAlias(this.selectorExprToString(selectorExpr.X), selectorExpr.Sel.Name), selectorExpr.Sel.Name)
+			return packagePath + "." + name
 		} else {
-			return this.typeToString(selectorExpr.X, true) + "." + selectorExpr.Sel.Name
+			return this.selectorExprToString(selectorExpr.X) + "." + selectorExpr.Sel.Name
 		}
 	}
 
 	structType, ok := t.(*ast.StructType)
 	if ok {
-		return "struct " + strings.Replace(this.structBodyToString(structType), "\n", " ", -1)
+		return "struct " + strings.Replace(this.anonymousStructBodyToString(structType), "\n", " ", -1)
 	}
 
 	ellipsis, ok := t.(*ast.Ellipsis)
@@ -965,6 +1538,26 @@ func (this *analysisTool) typeToString(t ast.Expr, convertTypeToUnqiueType bool)
 		return " (" + this.typeToString(parenExpr.X, convertTypeToUnqiueType) + ")"
 	}
 
+	// 泛型实例化, 例如 List[T], Map[K, V]
+	if genericBaseType(t) != t {
+		typeArgs := []string{}
+		for _, typeArg := range genericTypeArgs(t) {
+			typeArgs = append(typeArgs, this.typeToString(typeArg, convertTypeToUnqiueType))
+		}
+		return this.typeToString(genericBaseType(t), convertTypeToUnqiueType) + "[" + strings.Join(typeArgs, ", ") + "]"
+	}
+
+	// 类型约束, 例如 ~int | ~string
+	unaryExpr, ok := t.(*ast.UnaryExpr)
+	if ok {
+		return unaryExpr.Op.String() + this.typeToString(unaryExpr.X, convertTypeToUnqiueType)
+	}
+
+	binaryExpr, ok := t.(*ast.BinaryExpr)
+	if ok {
+		return this.typeToString(binaryExpr.X, convertTypeToUnqiueType) + " " + binaryExpr.Op.String() + " " + this.typeToString(binaryExpr.Y, convertTypeToUnqiueType)
+	}
+
 	log.Error("typeToString ", reflect.TypeOf(t), " file=", this.currentFile, " expr=", this.content(t))
 
 	return ""
@@ -984,6 +1577,10 @@ func (this *analysisTool) selectorExprToString(t ast.Expr) (string) {
 
 func (this *analysisTool)  addPackagePathWhenStruct(fieldType string) string {
 
+	if this.isTypeParam(fieldType) {
+		return fieldType
+	}
+
 	searchPackages := []string{this.currentPackagePath}
 
 	for _, import1 := range this.currentFileImports {
@@ -1004,38 +1601,92 @@ func (this *analysisTool)  addPackagePathWhenStruct(fieldType string) string {
 		}
 	}
 
+	for _, meta := range this.typeAliasMetas {
+		if sliceContains(searchPackages, meta.PackagePath) && meta.Name == fieldType {
+			packagePath, name := this.followTypeAlias(meta.PackagePath, fieldType)
+			return packagePath + "." + name
+		}
+	}
+
+	// 非内置类型只能在当前包中定义, 加上包路径避免和其他包中的同名类型混淆
+	if !this.isGoBaseType(fieldType) {
+		return this.currentPackagePath + "." + fieldType
+	}
+
 	return fieldType
 }
 
 func (this *analysisTool) findAliasByPackagePath(packagePath string) string {
 	result := ""
 
+	absPath := this.findPackageDir(packagePath)
+	if absPath != "" {
+		result = findGoPackageNameInDirPath(absPath)
+	}
+
+	log.Debugf("packagepath=%s, alias=%s\n", packagePath, result)
+
+	return result
+}
+
+/**
+ * 查找包路径对应的源码目录, 依次查找vendor目录, 当前文件所属的go.mod模块, GOPATH目录
+ */
+func (this *analysisTool) findPackageDir(packagePath string) string {
+
+	dir := this.config.CodeDir
+	if this.currentFile != "" {
+		dir = path.Dir(this.currentFile)
+	}
+
+	return this.findPackageDirFrom(dir, packagePath)
+}
+
+/**
+ * 在fromDir目录的go文件中import packagePath时, 查找包路径对应的源码目录
+ */
+func (this *analysisTool) findPackageDirFrom(fromDir string, packagePath string) string {
+
 	if this.config.VendorDir != "" {
 		absPath := path.Join(this.config.VendorDir, packagePath)
 		if PathExists(absPath) {
-			result = findGoPackageNameInDirPath(absPath)
+			return absPath
+		}
+	}
+
+	// 依次查找上级目录中的vendor目录
+	for dir := fromDir; dir != "/" && dir != "."; dir = path.Dir(dir) {
+		absPath := path.Join(dir, "vendor", packagePath)
+		if PathExists(absPath) {
+			return absPath
+		}
+	}
+
+	module := this.findModule(fromDir)
+	if module != nil {
+		absPath := this.findPackageDirInModule(module, packagePath)
+		if absPath != "" && PathExists(absPath) {
+			return absPath
 		}
 	}
 
 	if this.config.GopathDir != "" {
 		absPath := path.Join(this.config.GopathDir, "src", packagePath)
 		if PathExists(absPath) {
-			result = findGoPackageNameInDirPath(absPath)
+			return absPath
 		}
 	}
 
-	log.Debugf("packagepath=%s, alias=%s\n", packagePath, result)
-
-	return result
+	return ""
 }
 
-func (this*analysisTool) existStructOrInterfaceInPackage(typeName string, packageName string) bool {
-	structMeta1 := this.findStruct(this.currentPackagePath, typeName)
+func (this*analysisTool) existStructOrInterfaceInPackage(typeName string, packagePath string) bool {
+	structMeta1 := this.findStruct(packagePath, typeName)
 	if structMeta1 != nil {
 		return true
 	}
 
-	interfaceMeta1 := this.findInterfaceMeta(this.currentPackagePath, typeName)
+	interfaceMeta1 := this.findInterfaceMeta(packagePath, typeName)
 	if interfaceMeta1 != nil {
 		return true
 	}
@@ -1043,8 +1694,8 @@ func (this*analysisTool) existStructOrInterfaceInPackage(typeName string, packag
 	return false
 }
 
-func (this*analysisTool) existTypeAliasInPackage(typeName string, packageName string) bool {
-	meta1 := this.findTypeAlias(this.currentPackagePath, typeName)
+func (this*analysisTool) existTypeAliasInPackage(typeName string, packagePath string) bool {
+	meta1 := this.findTypeAlias(packagePath, typeName)
 	if meta1 != nil {
 		return true
 	}
@@ -1061,8 +1712,7 @@ func (this *analysisTool) findPackagePathByAlias(alias string, structName string
 		}
 
 		if this.existTypeAliasInPackage(structName, this.currentPackagePath) {
-			// 忽略别名类型
-			return ""
+			return this.currentPackagePath
 		}
 
 		matchedImportMetas := []*importMeta{}
@@ -1083,8 +1733,7 @@ func (this *analysisTool) findPackagePathByAlias(alias string, structName string
 				}
 
 				if this.existTypeAliasInPackage(structName, matchedImportMeta.Path) {
-					// 忽略别名类型
-					return ""
+					return matchedImportMeta.Path
 				}
 
 			}
@@ -1128,8 +1777,7 @@ func (this *analysisTool) findPackagePathByAlias(alias string, structName string
 				}
 
 				if this.existTypeAliasInPackage(structName, matchedImportMeta.Path) {
-					// 忽略别名类型
-					return ""
+					return matchedImportMeta.Path
 				}
 
 			}
@@ -1145,7 +1793,10 @@ func (this *analysisTool) findPackagePathByAlias(alias string, structName string
 
 }
 
-func (this *analysisTool) interfaceBodyToString(interfaceType *ast.InterfaceType) string {
+/**
+ * 匿名interface类型, 例如 map[string]interface{ Add() }
+ */
+func (this *analysisTool) anonymousInterfaceBodyToString(interfaceType *ast.InterfaceType) string {
 
 	result := " {\n"
 
@@ -1165,6 +1816,23 @@ func (this *analysisTool) interfaceBodyToString(interfaceType *ast.InterfaceType
 
 }
 
+/**
+ * 匿名struct类型, 例如 []struct{ a int }
+ */
+func (this *analysisTool) anonymousStructBodyToString(structType *ast.StructType) string {
+
+	result := "{\n"
+
+	for _, field := range structType.Fields.List {
+		result += "  " + this.fieldToString(field) + "\n"
+	}
+
+	result += "}"
+
+	return result
+
+}
+
 func (this *analysisTool) content(t ast.Expr) string {
 	bytes, err := ioutil.ReadFile(this.currentFile)
 	if err != nil {
@@ -1172,51 +1840,57 @@ func (this *analysisTool) content(t ast.Expr) string {
 		return ""
 	}
 
-	return string(bytes[t.Pos() - 1:t.End() - 1])
+	start := this.currentFset.Position(t.Pos()).Offset
+	end := this.currentFset.Position(t.End()).Offset
+
+	return string(bytes[start:end])
 }
 
 /**
- * 查找interface有哪些实现的Struct
+ * 查找interface有哪些实现的Struct, 按照Go的方法集规则判断T和*T是否实现了interface
  */
-func (this *analysisTool) findInterfaceImpls(interfaceMeta1 *interfaceMeta) []*structMeta {
-	metas := [] *structMeta{}
+func (this *analysisTool) findInterfaceImpls(interfaceMeta1 *interfaceMeta) []*interfaceImpl {
+	impls := []*interfaceImpl{}
 
-	for _, structMeta1 := range this.structMetas {
-		if sliceContainsSlice(structMeta1.MethodSigns, interfaceMeta1.MethodSigns) {
-			metas = append(metas, structMeta1)
-		}
+	interfaceMethods, complete := this.interfaceMethodSet(interfaceMeta1)
+	if !complete {
+		log.Debugf("interface %s.%s 嵌入了未知的interface, 方法集不完整", interfaceMeta1.PackagePath, interfaceMeta1.Name)
 	}
 
-	return metas
-}
-
-func (this *analysisTool) UML() string {
-
-	uml := ""
-
 	for _, structMeta1 := range this.structMetas {
-		uml += structMeta1.UML
-		uml += "\n"
-	}
 
-	for _, interfaceMeta1 := range this.interfaceMetas {
-		uml += interfaceMeta1.UML
-		uml += "\n"
-	}
+		implemented, pointerOnly, ok := this.typesImplements(structMeta1, interfaceMeta1)
 
-	for _, d := range this.dependencyRelations {
-		uml += d.uml
-		uml += "\n"
-	}
+		if !ok {
+			// 没有类型信息时, 使用语法树收集的方法集判断
+			if !complete || len(interfaceMethods) == 0 {
+				continue
+			}
+
+			if methodSetContains(this.structMethodSet(structMeta1, false), interfaceMethods) {
+				implemented = true
+			} else if methodSetContains(this.structMethodSet(structMeta1, true), interfaceMethods) {
+				implemented = true
+				pointerOnly = true
+			}
+		}
 
-	for _, interfaceMeta1 := range this.interfaceMetas {
-		structMetas := this.findInterfaceImpls(interfaceMeta1)
-		for _, structMeta := range structMetas {
-			uml += structMeta.implInterfaceUML(interfaceMeta1)
+		if implemented {
+			impls = append(impls, &interfaceImpl{
+				structMeta:  structMeta1,
+				PointerOnly: pointerOnly,
+			})
 		}
 	}
 
-	return "@startuml\n" + uml + "@enduml"
+	return impls
+}
+
+/**
+ * 使用配置的输出格式生成类图
+ */
+func (this *analysisTool) UML() string {
+	return this.newRenderer().render()
 }
 
 func (this*analysisTool) OutputToFile(logfile string) {
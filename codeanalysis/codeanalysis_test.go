@@ -2,16 +2,50 @@ package codeanalysis
 
 import (
 	"testing"
+	"encoding/xml"
+	"io"
 	"github.com/stvp/assert"
 	"fmt"
 	log "github.com/Sirupsen/logrus"
 	"os"
+	"strings"
+	"regexp"
+	"strconv"
 )
 
 
 var gopathDir = os.Getenv("GOPATH")
 var testdataPath = gopathDir + "/src/github.com/maobuji/go-package-plantuml/testdata"
 
+/**
+ * 分析testdata中的目录, CodeDir, GopathDir和VendorDir由目录决定, 其他配置来自config
+ */
+func analysisTestdata(dir string, config Config) *analysisTool {
+	config.CodeDir = testdataPath + "/" + dir
+	config.GopathDir = gopathDir
+	config.VendorDir = testdataPath + "/" + dir + "/vendor"
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+	return analysisTool1
+}
+
+/**
+ * 分析结果中的类型名, 先struct后interface, 按照解析的顺序, name返回空字符串的类型不包括
+ */
+func typeNames(analysisTool1 *analysisTool, name func(typeMeta1 typeMeta) string) []string {
+	result := []string{}
+	for _, structMeta1 := range analysisTool1.structMetas {
+		if value := name(structMeta1); value != "" {
+			result = append(result, value)
+		}
+	}
+	for _, interfaceMeta1 := range analysisTool1.interfaceMetas {
+		if value := name(interfaceMeta1); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
 func Test_findGoPackageNameInDirPath(t *testing.T) {
 	assert.Equal(t, "b", findGoPackageNameInDirPath(testdataPath + "/b"))
 	assert.Equal(t, "sub2", findGoPackageNameInDirPath(testdataPath + "/b/sub"))
@@ -111,17 +145,1183 @@ func Test_uml(t *testing.T) {
 
 	assert.Equal(t, 3, len(analysisTool1.interfaceMetas))
 	interfaceMeta := analysisTool1.interfaceMetas[0]
-	assert.Equal(t, "namespace git.oschina.net\\\\jscode\\\\go_package_plantuml\\\\testdata\\\\uml {\n interface IA  {\n  Add()\n} \n}", interfaceMeta.UML)
+	assert.Equal(t, "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\uml {\n interface IA  {\n  +Add()\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(interfaceMeta))
 
 	assert.Equal(t, 3, len(analysisTool1.structMetas))
 	structMeta1 := analysisTool1.structMetas[0]
-	assert.Equal(t, "namespace git.oschina.net\\\\jscode\\\\go_package_plantuml\\\\testdata\\\\uml {\n class SA {\n  a int\n  b sync.Mutex\n  c sub2.Sub2A\n  m map[string]sub2.Sub2A\n} \n}", structMeta1.UML)
+	assert.Equal(t, "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\uml {\n class SA {\n  -a int\n  -b sync.Mutex\n  -c sub2.Sub2A\n  -m map[string]sub2.Sub2A\n  +Add() <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(structMeta1))
 
 	interfaceImpls := analysisTool1.findInterfaceImpls(interfaceMeta)
 	assert.Equal(t, 2, len(interfaceImpls))
-	assert.Equal(t, "git.oschina.net\\\\jscode\\\\go_package_plantuml\\\\testdata\\\\uml.IA <|- git.oschina.net\\\\jscode\\\\go_package_plantuml\\\\testdata\\\\uml.SA\n", interfaceImpls[0].implInterfaceUML(interfaceMeta))
+	assert.Equal(t, "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\uml.IA <|- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\uml.SA : <<pointer>>\n", newPlantUMLRenderer(analysisTool1).implToUML(interfaceMeta, interfaceImpls[0]))
+
+	// Sub2I的方法参数依赖sub.SA
+	assert.Equal(t, 3, len(analysisTool1.dependencyRelations))
+	assert.Equal(t, "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\uml.SA *-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\uml\\\\sub2.Sub2A : c", newPlantUMLRenderer(analysisTool1).relationToUML(analysisTool1.dependencyRelations[0]))
+
+}
+
+/**
+ * 测试go.mod模块路径; replace到本地目录; 模块缓存中的依赖
+ */
+func Test_gomod(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/mod/app",
+		ModCacheDir: testdataPath + "/mod/modcache",
+		IgnoreDirs:[]string{},
+	}
+
+	result := AnalysisCode(config)
+
+	analysisTool1, _ := result.(*analysisTool)
+
+	assert.Equal(t, 1, len(analysisTool1.interfaceMetas))
+	interfaceMeta := analysisTool1.interfaceMetas[0]
+	assert.Equal(t, "example.com/app/service", interfaceMeta.PackagePath)
+	assert.Equal(t, "Save(example.com/dep/store.Item)example.com/Upper.U", interfaceMeta.MethodSigns[0])
 
+	assert.Equal(t, testdataPath + "/mod/dep/store", analysisTool1.findPackageDir("example.com/dep/store"))
+	assert.Equal(t, "upper", analysisTool1.findAliasByPackagePath("example.com/Upper"))
+	assert.Equal(t, "", analysisTool1.findPackageDir("example.com/unknown"))
+
+}
+
+
+/**
+ * 测试类型检查模式, 只有一个import . 包时语法树无法推断类型所在的包
+ */
+func Test_typecheck(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/typecheck",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
 	assert.Equal(t, 2, len(analysisTool1.dependencyRelations))
-	assert.Equal(t, "git.oschina.net\\\\jscode\\\\go_package_plantuml\\\\testdata\\\\uml.SA ---> git.oschina.net\\\\jscode\\\\go_package_plantuml\\\\testdata\\\\uml\\\\sub2.Sub2A : c", analysisTool1.dependencyRelations[0].uml)
 
-}
\ No newline at end of file
+	config.TypeCheck = true
+
+	analysisTool1, _ = AnalysisCode(config).(*analysisTool)
+	assert.Equal(t, 3, len(analysisTool1.dependencyRelations))
+	assert.Equal(t, "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\typecheck.Holder *-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\typecheck\\\\dot.Dot : d", newPlantUMLRenderer(analysisTool1).relationToUML(analysisTool1.dependencyRelations[0]))
+
+	config.CodeDir = testdataPath + "/b"
+
+	analysisTool1, _ = AnalysisCode(config).(*analysisTool)
+	interfaceMeta := analysisTool1.findInterfaceMeta("github.com/maobuji/go-package-plantuml/testdata/b", "IA")
+	assert.Equal(t, "Add(github.com/maobuji/go-package-plantuml/testdata/b/sub.SubSA,sync.Locker,github.com/maobuji/go-package-plantuml/testdata/b.B,github.com/maobuji/go-package-plantuml/testdata/b/suba.SubSa1)", interfaceMeta.MethodSigns[0])
+	structMetas := analysisTool1.findInterfaceImpls(interfaceMeta)
+	assert.Equal(t, 1, len(structMetas))
+	assert.Equal(t, "SB", structMetas[0].Name)
+
+}
+
+
+/**
+ * 测试方法集: 指针接收者; 嵌入struct提升的方法; 嵌入interface; 不同包中的同名类型
+ */
+func Test_methodset(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		config := Config{
+			CodeDir: testdataPath + "/methodset",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			TypeCheck: typeCheck,
+		}
+
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+		implsToString := func(interfaceName string) string {
+			interfaceMeta := analysisTool1.findInterfaceMeta("github.com/maobuji/go-package-plantuml/testdata/methodset", interfaceName)
+			result := ""
+			for _, impl := range analysisTool1.findInterfaceImpls(interfaceMeta) {
+				result += impl.Name
+				if impl.PointerOnly {
+					result += "(pointer)"
+				}
+				result += " "
+			}
+			return result
+		}
+
+		assert.Equal(t, "File LogFile PtrLogFile ", implsToString("Reader"), typeCheck)
+		assert.Equal(t, "File(pointer) LogFile(pointer) PtrLogFile ", implsToString("Closer"), typeCheck)
+		assert.Equal(t, "File(pointer) LogFile(pointer) PtrLogFile ", implsToString("ReadCloser"), typeCheck)
+		assert.Equal(t, "", implsToString("StatusSetter"), typeCheck)
+	}
+
+}
+
+
+/**
+ * 测试泛型: 类型参数; 类型约束interface; 泛型接收者的方法; 泛型实例化的字段依赖
+ */
+func Test_generics(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		config := Config{
+			CodeDir: testdataPath + "/generics",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			TypeCheck: typeCheck,
+		}
+
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+		packagePath := "github.com/maobuji/go-package-plantuml/testdata/generics"
+		namespace := "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generics {\n "
+
+		assert.Equal(t, 5, len(analysisTool1.structMetas), typeCheck)
+		assert.Equal(t, namespace + "class Box<T> {\n  -value T\n  +Get() T <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Box")), typeCheck)
+		assert.Equal(t, namespace + "class Pair<K comparable, V Number> {\n  -key K\n  -value V\n  +Key() K\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Pair")), typeCheck)
+		assert.Equal(t, namespace + "interface Number <<~int | ~int64 | ~float64>>  {\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "Number")), typeCheck)
+		assert.Equal(t, namespace + "interface Getter<T>  {\n  +Get() T\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "Getter")), typeCheck)
+
+		assert.Equal(t, []string{"Get()T"}, analysisTool1.findStruct(packagePath, "Box").MethodSigns, typeCheck)
+		assert.Equal(t, []string{"Push(T)"}, analysisTool1.findStruct(packagePath, "List").MethodSigns, typeCheck)
+		assert.Equal(t, []string{"Key()K"}, analysisTool1.findStruct(packagePath, "Pair").MethodSigns, typeCheck)
+
+		relations := []string{}
+		for _, d := range analysisTool1.dependencyRelations {
+			relations = append(relations, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generics.", "", -1))
+		}
+		assert.Equal(t, []string{
+			"List *-- \"*\" Box : items",
+			"List o-- List : next",
+			"Registry *-- \"*\" Box : users",
+			"Registry --> User : users",
+			"Registry [string] *-- \"*\" Pair : pairs",
+		}, relations, typeCheck)
+	}
+
+}
+
+
+/**
+ * 测试有方法的非struct命名类型
+ */
+func Test_namedType(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		config := Config{
+			CodeDir: testdataPath + "/named",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			TypeCheck: typeCheck,
+		}
+
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+		packagePath := "github.com/maobuji/go-package-plantuml/testdata/named"
+		namespace := "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\named {\n "
+
+		assert.Equal(t, 5, len(analysisTool1.structMetas), typeCheck)
+		assert.Equal(t, 1, len(analysisTool1.typeAliasMetas), typeCheck)
+		assert.Equal(t, namespace + "class Status <<int>> {\n  +String() string\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Status")), typeCheck)
+		assert.Equal(t, namespace + "class Middleware <<func(http.Handler)http.Handler>> {\n  +Wrap(h http.Handler) http.Handler\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Middleware")), typeCheck)
+		assert.Equal(t, []string{"String()string"}, analysisTool1.findStruct(packagePath, "Status").MethodSigns, typeCheck)
+
+		impls := analysisTool1.findInterfaceImpls(analysisTool1.findInterfaceMeta(packagePath, "Handler"))
+		assert.Equal(t, 2, len(impls), typeCheck)
+		assert.Equal(t, "HandlerFunc", impls[0].Name, typeCheck)
+		assert.Equal(t, "Handlers", impls[1].Name, typeCheck)
+
+		relations := []string{}
+		for _, d := range analysisTool1.dependencyRelations {
+			relations = append(relations, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\named.", "", -1))
+		}
+		assert.Equal(t, []string{
+			"Handlers *-- \"*\" HandlerFunc",
+			"Server *-- Status : status",
+			"Server *-- Handlers : handlers",
+		}, relations, typeCheck)
+	}
+
+}
+
+
+/**
+ * 测试const块中的枚举
+ */
+func Test_enum(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/enum",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+	packagePath := "github.com/maobuji/go-package-plantuml/testdata/enum"
+	namespace := "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\enum {\n "
+
+	assert.Equal(t, 4, len(analysisTool1.structMetas))
+	assert.Equal(t, namespace + "enum State {\n  StateIdle\n  StateRunning\n  StateStopped\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "State")))
+	assert.Equal(t, []string{"Red", "Green"}, analysisTool1.findStruct(packagePath, "Color").EnumValues)
+	assert.Equal(t, []string{"Low", "High"}, analysisTool1.findStruct(packagePath, "Level").EnumValues)
+
+	assert.Equal(t, 2, len(analysisTool1.dependencyRelations))
+	assert.Equal(t, "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\enum.Machine *-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\enum.State : state", newPlantUMLRenderer(analysisTool1).relationToUML(analysisTool1.dependencyRelations[0]))
+	assert.Equal(t, "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\enum.Machine *-- \"*\" github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\enum.Color : colors", newPlantUMLRenderer(analysisTool1).relationToUML(analysisTool1.dependencyRelations[1]))
+
+}
+
+
+/**
+ * 测试类型别名 type A = B
+ */
+func Test_typeAlias(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		config := Config{
+			CodeDir: testdataPath + "/alias",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			TypeCheck: typeCheck,
+			ShowTypeAliases: true,
+		}
+
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+		packagePath := "github.com/maobuji/go-package-plantuml/testdata/alias"
+
+		relations := []string{}
+		for _, d := range analysisTool1.dependencyRelations {
+			relations = append(relations, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\", "", -1))
+		}
+		assert.Equal(t, []string{
+			"alias.Service *-- alias\\\\model.User : user",
+			"alias.Service *-- \"*\" alias\\\\model.User : users",
+			"alias.Service *-- alias.Local : local",
+			// PtrUser是*model.User的别名
+			"alias.Service o-- alias\\\\model.User : ptr",
+			"alias\\\\model.Repo ..> alias\\\\model.User",
+			"alias.Saver ..> alias\\\\model.User",
+		}, relations, typeCheck)
+
+		impls := analysisTool1.findInterfaceImpls(analysisTool1.findInterfaceMeta(packagePath, "Saver"))
+		assert.Equal(t, 1, len(impls), typeCheck)
+		assert.Equal(t, "Repo", impls[0].Name, typeCheck)
+
+		typeAliasMeta1 := analysisTool1.findTypeAlias(packagePath, "Store")
+		assert.Equal(t, packagePath + "/model", typeAliasMeta1.TargetPackagePath, typeCheck)
+		assert.Equal(t, "Store", typeAliasMeta1.TargetName, typeCheck)
+		assert.Equal(t, "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\alias {\n class Store <<alias>> {\n} \n}\n" +
+			"github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\alias.Store ..> github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\alias\\\\model.Store : alias", newPlantUMLRenderer(analysisTool1).nodeToUML(typeAliasMeta1), typeCheck)
+	}
+
+}
+
+
+/**
+ * 测试嵌入: interface嵌入interface; struct嵌入struct, *struct, interface; 提升的字段和方法
+ */
+func Test_embed(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/embed",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+		ShowPromoted: true,
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+	packagePath := "github.com/maobuji/go-package-plantuml/testdata/embed"
+	namespace := "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\embed"
+
+	relations := []string{}
+	for _, d := range analysisTool1.dependencyRelations {
+		relations = append(relations, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), namespace + ".", "", -1))
+	}
+	assert.Equal(t, []string{
+		"Middle *.. Inner : <<embeds>>",
+		"Outer *.. Shallow : <<embeds>>",
+		"Outer *.. Middle : <<embeds>>",
+		"Pair *.. Left : <<embeds>>",
+		"Pair *.. Right : <<embeds>>",
+		"Wrapper *.. Pair : <<embeds>>",
+		"Reader <|-- ReadCloser",
+		"Entity *.. Base : <<embeds>>",
+		"Entity o.. Named : <<embeds>>",
+		"Entity *.. Reader : <<embeds>>",
+		"Record o.. Base : <<embeds>>",
+	}, relations)
+
+	// 嵌入的外部interface也显示在interface中
+	assert.Equal(t, "namespace " + namespace + " {\n interface ReadWriter  {\n  io.Reader\n  io.Writer\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "ReadWriter")))
+
+	assert.Equal(t, "namespace " + namespace + " {\n interface ReadCloser  {\n  Reader\n  +Close() error\n  .. embedded Reader ..\n  +Read(p []byte) (int, error)\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "ReadCloser")))
+
+	// Base和Named都有Save方法, 不会提升
+	assert.Equal(t, "namespace " + namespace + " {\n class Entity {\n  +Base\n  +*Named\n  +Reader\n  +Title string\n" +
+		"  .. embedded Base ..\n  +ID int\n  .. embedded Named ..\n  +Name string\n  .. embedded Reader ..\n  +Read(p []byte) (int, error)\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Entity")))
+
+	// 外层的ID覆盖了Base的ID
+	assert.Equal(t, "namespace " + namespace + " {\n class Record {\n  +*Base\n  +ID string\n  .. embedded Base ..\n  +Save() error <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Record")))
+
+	// 同名的成员只提升深度最浅的: Shallow.X覆盖了Middle中Inner.X, Inner.Y没有冲突
+	assert.Equal(t, "namespace " + namespace + " {\n class Outer {\n  +Shallow\n  +Middle\n" +
+		"  .. embedded Shallow ..\n  +X string\n  .. embedded Middle ..\n  +Y int\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Outer")))
+
+	// 同一个嵌入类型中同样深度的Left.Z和Right.Z冲突
+	assert.Equal(t, "namespace " + namespace + " {\n class Wrapper {\n  +Pair\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Wrapper")))
+
+	config.ShowPromoted = false
+	analysisTool1, _ = AnalysisCode(config).(*analysisTool)
+	assert.Equal(t, "namespace " + namespace + " {\n class Record {\n  +*Base\n  +ID string\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Record")))
+
+}
+
+
+/**
+ * 测试方法的显示: 参数名; 可变参数; 返回值; 可见性; 指针接收者; interface继承的方法
+ */
+func Test_methods(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/methods",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+	packagePath := "github.com/maobuji/go-package-plantuml/testdata/methods"
+	namespace := "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\methods"
+
+	assert.Equal(t, "namespace " + namespace + " {\n class Client {\n  -name string\n" +
+		"  +Do(method string, url string, opts ...Option) (int, error) <<pointer>>\n" +
+		"  +Name() string\n" +
+		"  -read(r io.Reader, a, b int) (n int, err error) <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Client")))
+
+	assert.Equal(t, "namespace " + namespace + " {\n interface ReadCloser  {\n  Reader\n  +Close() error\n" +
+		"  .. embedded Reader ..\n  +Read(p []byte) (n int, err error)\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "ReadCloser")))
+
+}
+
+
+/**
+ * 测试详细程度: full; public-api; signatures; names-only; 成员数量限制
+ */
+func Test_detail(t *testing.T) {
+
+	packagePath := "github.com/maobuji/go-package-plantuml/testdata/detail"
+	namespace := "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\detail {\n "
+
+	analysis := func(detail string, maxMembers int) *analysisTool {
+		config := Config{
+			CodeDir: testdataPath + "/detail",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			Detail: detail,
+			MaxMembers: maxMembers,
+		}
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+		return analysisTool1
+	}
+
+	analysisTool1 := analysis("", 0)
+	assert.Equal(t, namespace + "class Service {\n  +Name string\n  -count int\n  -cache map[string]string\n  +Store store\n" +
+		"  +Start() error <<pointer>>\n  -stop() <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Service")))
+	assert.True(t, strings.Contains(analysisTool1.UML(), "detail.store"))
+
+	analysisTool1 = analysis(DetailPublicAPI, 0)
+	assert.Equal(t, namespace + "class Service {\n  +Name string\n  +Store store\n  +Start() error <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Service")))
+	assert.Equal(t, namespace + "interface Handler  {\n  +Handle()\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "Handler")))
+	// 未导出的类型和指向它的关系都不显示
+	assert.False(t, strings.Contains(analysisTool1.UML(), "detail.store"))
+
+	analysisTool1 = analysis(DetailSignatures, 0)
+	assert.Equal(t, namespace + "class Service {\n  +Start() error <<pointer>>\n  -stop() <<pointer>>\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Service")))
+
+	analysisTool1 = analysis(DetailNamesOnly, 0)
+	assert.Equal(t, namespace + "class Service {\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Service")))
+	assert.Equal(t, namespace + "interface Handler  {\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findInterfaceMeta(packagePath, "Handler")))
+
+	analysisTool1 = analysis(DetailFull, 2)
+	assert.Equal(t, namespace + "class Service {\n  +Name string\n  -count int\n  ... 4 more\n} \n}", newPlantUMLRenderer(analysisTool1).nodeToUML(analysisTool1.findStruct(packagePath, "Service")))
+
+}
+
+
+/**
+ * 测试字段依赖interface和枚举
+ */
+func Test_relationToInterface(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		config := Config{
+			CodeDir: testdataPath + "/relation",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			TypeCheck: typeCheck,
+		}
+
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+		relations := []string{}
+		for _, d := range analysisTool1.dependencyRelations {
+			relations = append(relations, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\", "", -1))
+		}
+		assert.Equal(t, []string{
+			"relation.Service o-- relation.Storage : store",
+			"relation.Service o-- \"*\" relation\\\\log.Logger : loggers",
+			"relation.Service *-- relation.Level : level",
+			"relation.Service [string] o-- \"*\" relation.Storage : byName",
+		}, relations, typeCheck)
+	}
+
+}
+
+
+/**
+ * 测试字段的形状: 值为组合; 指针为聚合; 切片, 数组, map的多重性和限定符; chan和func为关联
+ */
+func Test_fieldShape(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		config := Config{
+			CodeDir: testdataPath + "/shape",
+			GopathDir :gopathDir,
+			IgnoreDirs:[]string{},
+			TypeCheck: typeCheck,
+		}
+
+		analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+		relations := []string{}
+		for _, d := range analysisTool1.dependencyRelations {
+			relations = append(relations, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\shape.", "", -1))
+		}
+		assert.Equal(t, []string{
+			"Order *-- Request : request",
+			"Order o-- Response : response",
+			"Order o-- \"*\" Event : events",
+			"Order *-- \"3\" Event : last",
+			"Order [int64] o-- \"*\" Event : byID",
+			"Order --> Event : queue <<chan>>",
+			"Order --> Event : notify <<chan>>",
+			"Order --> Request : handler <<func>>",
+			"Order --> Response : handler <<func>>",
+			"Order *-- \"*\" Event : batches",
+			"Order *-- \"*\" Response : responses",
+		}, relations, typeCheck)
+	}
+
+}
+
+
+/**
+ * 测试方法中的依赖: 参数和返回值; 方法体中的复合字面量, 类型转换, 局部变量; 关系来源的开关
+ */
+func Test_uses(t *testing.T) {
+
+	for _, typeCheck := range []bool{false, true} {
+
+		relations := func(sources []string) []string {
+			config := Config{
+				CodeDir: testdataPath + "/uses",
+				GopathDir :gopathDir,
+				IgnoreDirs:[]string{},
+				TypeCheck: typeCheck,
+				RelationSources: sources,
+			}
+			analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+			result := []string{}
+			for _, d := range analysisTool1.dependencyRelations {
+				result = append(result, strings.Replace(newPlantUMLRenderer(analysisTool1).relationToUML(d), "github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\", "", -1))
+			}
+			return result
+		}
+
+		assert.Equal(t, []string{
+			"uses.Handler o-- uses.Logger : logger",
+			"uses.Handler ..> uses.Request",
+			"uses.Handler ..> uses\\\\model.Option",
+			"uses.Handler ..> uses.Order",
+			"uses.Logger ..> uses.Request",
+		}, relations(nil), typeCheck)
+
+		assert.Equal(t, []string{
+			"uses.Handler o-- uses.Logger : logger",
+			"uses.Handler ..> uses.Request",
+			"uses.Handler ..> uses\\\\model.Option",
+			"uses.Handler ..> uses.Order",
+			"uses.Handler ..> uses.Cache",
+			"uses.Handler ..> uses.Status",
+			"uses.Handler ..> uses\\\\model.Item",
+			"uses.Logger ..> uses.Request",
+		}, relations([]string{RelationFields, RelationSignatures, RelationBodies}), typeCheck)
+
+		assert.Equal(t, []string{
+			"uses.Handler o-- uses.Logger : logger",
+		}, relations([]string{RelationFields}), typeCheck)
+
+		assert.Equal(t, []string{
+			"uses.Handler ..> uses.Cache",
+			"uses.Handler ..> uses.Status",
+			"uses.Handler ..> uses\\\\model.Item",
+			"uses.Handler ..> uses.Order",
+		}, relations([]string{RelationBodies}), typeCheck)
+	}
+
+}
+
+/**
+ * 测试Mermaid类图: 包对应namespace; 成员和可见性; 关系的多重性和限定符; 泛型类名的转义
+ */
+func Test_mermaid(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/uml",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+		Format: FormatMermaid,
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+	mermaid := analysisTool1.UML()
+
+	id := "github_com_maobuji_go_package_plantuml_testdata_uml"
+
+	assert.True(t, strings.HasPrefix(mermaid, "classDiagram\nnamespace " + id + " {\n"))
+	assert.True(t, strings.Contains(mermaid, "  class " + id + "_SA[\"SA\"] {\n    -a int\n    -b sync.Mutex\n    -c sub2.Sub2A\n" +
+		"    -m map[string]sub2.Sub2A\n    +Add() «pointer»\n  }\n"))
+	assert.True(t, strings.Contains(mermaid, "  class " + id + "_IA[\"IA\"] {\n    <<interface>>\n    +Add()\n  }\n"))
+	assert.True(t, strings.Contains(mermaid, "\n" + id + "_SA *-- \"*\" " + id + "_sub2_Sub2A : [string] m\n"))
+	assert.True(t, strings.Contains(mermaid, "\n" + id + "_sub2_Sub2I ..> " + id + "_sub_SA\n"))
+	assert.True(t, strings.Contains(mermaid, "\n" + id + "_IA <|.. " + id + "_SA : «pointer»\n"))
+
+	// 同一份分析结果输出PlantUML
+	analysisTool1.config.Format = FormatPlantUML
+	assert.True(t, strings.HasPrefix(analysisTool1.UML(), "@startuml\n"))
+
+	config.CodeDir = testdataPath + "/generics"
+	analysisTool1, _ = AnalysisCode(config).(*analysisTool)
+	assert.True(t, strings.Contains(analysisTool1.UML(), "_Pair[\"Pair#lt;K comparable, V Number#gt;\"] {\n"))
+
+}
+
+/**
+ * 测试Graphviz DOT图: 包对应cluster; HTML表格的节点; 嵌入, 实现, chan和map字段的边
+ */
+func Test_dot(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/embed",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+		Format: FormatDot,
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+	dot := analysisTool1.UML()
+
+	packagePath := "github.com/maobuji/go-package-plantuml/testdata/embed"
+
+	assert.True(t, strings.HasPrefix(dot, "digraph G {\n"))
+	assert.True(t, strings.HasSuffix(dot, "}\n"))
+	assert.True(t, strings.Contains(dot, "  subgraph cluster_github_com_maobuji_go_package_plantuml_testdata_embed {\n    label=\"" + packagePath + "\";\n"))
+	assert.True(t, strings.Contains(dot, "    \"" + packagePath + ".Reader\" [label=<<table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">" +
+		"<tr><td><i>«interface»</i><br/><b>Reader</b></td></tr><tr><td align=\"left\" balign=\"left\">+Read(p []byte) (int, error)</td></tr></table>>];\n"))
+	assert.True(t, strings.Contains(dot, "  \"" + packagePath + ".ReadCloser\" -> \"" + packagePath + ".Reader\" [arrowhead=empty];\n"))
+	assert.True(t, strings.Contains(dot, "  \"" + packagePath + ".Record\" -> \"" + packagePath + ".Base\" [style=dashed, dir=both, arrowtail=odiamond, arrowhead=none, label=\"«embeds»\"];\n"))
+	assert.True(t, strings.Contains(dot, "  \"" + packagePath + ".Entity\" -> \"" + packagePath + ".Reader\" [style=dashed, arrowhead=empty];\n"))
+
+	config.CodeDir = testdataPath + "/shape"
+	analysisTool1, _ = AnalysisCode(config).(*analysisTool)
+	dot = analysisTool1.UML()
+
+	packagePath = "github.com/maobuji/go-package-plantuml/testdata/shape"
+	assert.True(t, strings.Contains(dot, "  \"" + packagePath + ".Order\" -> \"" + packagePath + ".Event\" [dir=both, arrowtail=odiamond, arrowhead=none, label=\"byID\", headlabel=\"*\", taillabel=\"[int64]\"];\n"))
+	assert.True(t, strings.Contains(dot, "  \"" + packagePath + ".Order\" -> \"" + packagePath + ".Event\" [arrowhead=vee, label=\"queue «chan»\"];\n"))
+
+}
+
+/**
+ * 测试SVG图: 合法的XML; 被依赖的类型在上层; 节点不重叠
+ */
+func Test_svg(t *testing.T) {
+
+	config := Config{
+		CodeDir: testdataPath + "/uml",
+		GopathDir :gopathDir,
+		IgnoreDirs:[]string{},
+		Format: FormatSVG,
+	}
+
+	analysisTool1, _ := AnalysisCode(config).(*analysisTool)
+
+	svg := analysisTool1.UML()
+	assert.True(t, strings.HasPrefix(svg, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\""))
+	assert.True(t, strings.HasSuffix(svg, "</svg>\n"))
+	assert.True(t, strings.Contains(svg, ">-m map[string]sub2.Sub2A</text>\n"))
+
+	// SVG必须是合法的XML
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+		if err != nil {
+			break
+		}
+	}
+
+	renderer := &svgRenderer{tool: analysisTool1}
+	nodes, edges := renderer.layout()
+	assert.Equal(t, 6, len(nodes))
+	assert.Equal(t, 7, len(edges))
+
+	findNode := func(packagePath string, name string) *svgNode {
+		for _, node := range nodes {
+			if node.packagePath == packagePath && typeMetaName(node.meta) == name {
+				return node
+			}
+		}
+		return nil
+	}
+
+	packagePath := "github.com/maobuji/go-package-plantuml/testdata/uml"
+	sa := findNode(packagePath, "SA")
+	// 被依赖的类型在上层
+	assert.True(t, findNode(packagePath, "IA").layer < sa.layer)
+	assert.True(t, findNode(packagePath + "/sub2", "Sub2A").layer < sa.layer)
+	assert.True(t, findNode(packagePath + "/sub", "SA").layer < findNode(packagePath + "/sub2", "Sub2I").layer)
+
+	// 节点不重叠
+	for i, a := range nodes {
+		for _, b := range nodes[i + 1:] {
+			overlap := a.x < b.x + b.width && b.x < a.x + a.width && a.y < b.y + b.height && b.y < a.y + a.height
+			assert.False(t, overlap, typeMetaName(a.meta), typeMetaName(b.meta))
+		}
+	}
+
+	// 画布包含所有节点
+	assertSVGBounds(t, analysisTool1)
+
+}
+
+/**
+ * 测试包的import关系图: 包的种类; 同一个文件只计算一次; 合并标准库, vendor和外部模块; 各种输出格式
+ */
+func Test_packages(t *testing.T) {
+
+	edges := func(analysisTool1 *analysisTool) []string {
+		result := []string{}
+		for _, edge := range analysisTool1.packageEdges {
+			result = append(result, fmt.Sprintf("%s -> %s : %d",
+				strings.Replace(edge.source.Name, "github.com/maobuji/go-package-plantuml/testdata/packages/", "", -1),
+				strings.Replace(edge.target.Name, "github.com/maobuji/go-package-plantuml/testdata/packages/", "", -1), edge.Weight))
+		}
+		return result
+	}
+
+	analysisTool1 := analysisTestdata("packages", Config{View: ViewPackages})
+	assert.Equal(t, []string{
+		"app -> fmt : 2",
+		"app -> strings : 1",
+		"app -> github.com/ext/lib : 1",
+		"app -> model : 2",
+		"app -> example.com/unknown/driver : 1",
+		"model -> time : 1",
+		"github.com/ext/lib -> strings : 1",
+	}, edges(analysisTool1))
+
+	kinds := map[string]string{}
+	for _, node := range analysisTool1.packageNodes {
+		kinds[node.Name] = node.Kind
+	}
+	assert.Equal(t, packageInternal, kinds["github.com/maobuji/go-package-plantuml/testdata/packages/model"])
+	assert.Equal(t, PackageStdlib, kinds["fmt"])
+	assert.Equal(t, PackageVendor, kinds["github.com/ext/lib"])
+	assert.Equal(t, PackageExternal, kinds["example.com/unknown/driver"])
+
+	uml := analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, "package \"fmt\" as fmt <<stdlib>> {\n}\n"))
+	assert.True(t, strings.Contains(uml, "github_com_maobuji_go_package_plantuml_testdata_packages_app ..> fmt : 2\n"))
+
+	// 同一个文件import多个标准库时只计算一次
+	analysisTool1 = analysisTestdata("packages", Config{View: ViewPackages, CollapsePackages: []string{PackageStdlib, PackageVendor, PackageExternal}})
+	assert.Equal(t, []string{
+		"app -> stdlib : 2",
+		"app -> vendor : 1",
+		"app -> model : 2",
+		"app -> external : 1",
+		"model -> stdlib : 1",
+		"vendor -> stdlib : 1",
+	}, edges(analysisTool1))
+
+	analysisTool1 = analysisTestdata("packages", Config{View: ViewPackages, Format: FormatMermaid, CollapsePackages: []string{PackageStdlib}})
+	assert.True(t, strings.Contains(analysisTool1.UML(), "  stdlib[\"stdlib\"]:::stdlib\n"))
+	assert.True(t, strings.Contains(analysisTool1.UML(), "  github_com_maobuji_go_package_plantuml_testdata_packages_app -->|2| stdlib\n"))
+
+	analysisTool1 = analysisTestdata("packages", Config{View: ViewPackages, Format: FormatDot, CollapsePackages: []string{PackageStdlib}})
+	assert.True(t, strings.Contains(analysisTool1.UML(), "  \"github.com/maobuji/go-package-plantuml/testdata/packages/app\" -> \"stdlib\" [label=\"2\", weight=2];\n"))
+
+	analysisTool1 = analysisTestdata("packages", Config{View: ViewPackages, Format: FormatSVG, CollapsePackages: []string{PackageStdlib}})
+	assert.True(t, strings.Contains(analysisTool1.UML(), ">«stdlib»</text>"))
+	assertSVGBounds(t, analysisTool1)
+
+}
+
+/**
+ * 测试SVG的画布包含所有节点
+ */
+func assertSVGBounds(t *testing.T, analysisTool1 *analysisTool) {
+
+	match := regexp.MustCompile(`<svg [^>]*width="([0-9.]+)" height="([0-9.]+)"`).FindStringSubmatch(analysisTool1.UML())
+	assert.Equal(t, 3, len(match))
+	if len(match) != 3 {
+		return
+	}
+	width, _ := strconv.ParseFloat(match[1], 64)
+	height, _ := strconv.ParseFloat(match[2], 64)
+
+	renderer := &svgRenderer{tool: analysisTool1}
+	nodes, _ := renderer.layout()
+	assert.True(t, len(nodes) > 0)
+	for _, node := range nodes {
+		assert.True(t, node.x >= 0 && node.x + node.width <= width, node.header)
+		assert.True(t, node.y >= 0 && node.y + node.height <= height, node.header)
+	}
+}
+
+/**
+ * 测试循环依赖: 包和类型的循环, 自己引用自己不算循环; 循环报告; 各种输出格式中高亮循环的边
+ */
+func Test_cycles(t *testing.T) {
+
+	short := func(names []string) []string {
+		result := []string{}
+		for _, name := range names {
+			result = append(result, strings.Replace(name, "github.com/maobuji/go-package-plantuml/testdata/cycle/", "", -1))
+		}
+		return result
+	}
+
+	analysisTool1 := analysisTestdata("cycle", Config{DetectCycles: true, HighlightCycles: true})
+	assert.Equal(t, 2, len(analysisTool1.cycles))
+
+	assert.Equal(t, cyclePackages, analysisTool1.cycles[0].Kind)
+	assert.Equal(t, []string{"customer", "order"}, short(analysisTool1.cycles[0].Nodes))
+
+	// 自己引用自己的Node不算循环依赖
+	assert.Equal(t, cycleTypes, analysisTool1.cycles[1].Kind)
+	assert.Equal(t, []string{"customer.Customer", "order.Order", "order.Item"}, short(analysisTool1.cycles[1].Nodes))
+	assert.Equal(t, 2, analysisTool1.cycles[1].PackageCount)
+	assert.Equal(t, 4, len(analysisTool1.cycles[1].Edges))
+
+	report := analysisTool1.CycleReport()
+	assert.True(t, strings.Contains(report, "包循环依赖, 2个包"))
+	assert.True(t, strings.Contains(report, "类型循环依赖, 跨2个包"))
+	assert.True(t, strings.Contains(report, "cycle/order/order.go:8 字段 buyer\n"))
+	assert.True(t, strings.Contains(report, "cycle/customer/customer.go:11 方法 Orders\n"))
+	assert.True(t, strings.Contains(report, "cycle/customer/customer.go:4\n"))
+
+	uml := analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, "order.Order o-[#red]- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\cycle\\\\customer.Customer : buyer\n"))
+	assert.True(t, strings.Contains(uml, "order.Node o-- github.com"))
+
+	analysisTool1 = analysisTestdata("cycle", Config{DetectCycles: true, HighlightCycles: true, Format: FormatDot})
+	assert.True(t, strings.Contains(analysisTool1.UML(), "label=\"buyer\", color=red"))
+
+	analysisTool1 = analysisTestdata("cycle", Config{DetectCycles: true, HighlightCycles: true, Format: FormatMermaid})
+	assert.True(t, strings.Contains(analysisTool1.UML(), "style github_com_maobuji_go_package_plantuml_testdata_cycle_order_Order stroke:#ff0000"))
+
+	analysisTool1 = analysisTestdata("cycle", Config{DetectCycles: true, HighlightCycles: true, Format: FormatSVG, View: ViewPackages})
+	assert.True(t, strings.Contains(analysisTool1.UML(), "class=\"edge dashed cycle\""))
+
+	analysisTool1 = analysisTestdata("cycle", Config{DetectCycles: true, HighlightCycles: true, Format: FormatMermaid, View: ViewPackages})
+	assert.True(t, strings.Contains(analysisTool1.UML(), "  linkStyle 0 stroke:#ff0000\n"))
+
+}
+
+/**
+ * 测试架构规则: 包路径的匹配; 规则文件的加载; import和类型使用的违规; 配置错误时分析失败
+ */
+func Test_rules(t *testing.T) {
+
+	assert.True(t, matchPackagePattern("internal/domain/**", "internal/domain"))
+	assert.True(t, matchPackagePattern("internal/domain/**", "internal/domain/user/model"))
+	assert.False(t, matchPackagePattern("internal/domain/**", "internal/domainx"))
+	assert.True(t, matchPackagePattern("**/services", "internal/services"))
+	assert.True(t, matchPackagePattern("internal/*/db", "internal/infra/db"))
+	assert.False(t, matchPackagePattern("internal/*", "internal/infra/db"))
+
+	rules, err := LoadRules(testdataPath + "/rules/rules.json")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(rules))
+
+	analysisTool1 := analysisTestdata("rules", Config{Rules: rules})
+
+	violations := []string{}
+	for _, violation := range analysisTool1.RuleViolations() {
+		violations = append(violations, strings.Replace(violation.String(), testdataPath + "/rules/", "", -1))
+	}
+
+	// 接口类型的字段和参数不违反规则, 已经有字段关系的方法返回值不重复报告, 包级别的函数的参数也要检查
+	assert.Equal(t, []string{
+		"internal/domain/user.go:4: domain不能依赖infra: github.com/maobuji/go-package-plantuml/testdata/rules/internal/domain import了github.com/maobuji/go-package-plantuml/testdata/rules/internal/infra/db",
+		"internal/handlers/handler.go:9: handlers只能通过interface使用services: github.com/maobuji/go-package-plantuml/testdata/rules/internal/handlers.Handler 字段 users 使用了github.com/maobuji/go-package-plantuml/testdata/rules/internal/services.UserService, 只能通过interface使用",
+		"internal/handlers/factory.go:7: handlers只能通过interface使用services: github.com/maobuji/go-package-plantuml/testdata/rules/internal/handlers 函数 NewHandler 使用了github.com/maobuji/go-package-plantuml/testdata/rules/internal/services.UserService, 只能通过interface使用",
+	}, violations)
+
+	// check命令同时检查方法体中使用的具体类型
+	analysisTool1 = analysisTestdata("rules", Config{Rules: rules, RelationSources: []string{RelationFields, RelationSignatures, RelationBodies}})
+	assert.Equal(t, 5, len(analysisTool1.RuleViolations()))
+	assert.Equal(t, "handlers只能通过interface使用services: github.com/maobuji/go-package-plantuml/testdata/rules/internal/handlers.Admin 方法 Reset 使用了github.com/maobuji/go-package-plantuml/testdata/rules/internal/services.UserService, 只能通过interface使用",
+		strings.SplitN(analysisTool1.RuleViolations()[2].String(), ": ", 2)[1])
+	assert.Equal(t, "handlers只能通过interface使用services: github.com/maobuji/go-package-plantuml/testdata/rules/internal/handlers 函数 resetUsers 使用了github.com/maobuji/go-package-plantuml/testdata/rules/internal/services.UserService, 只能通过interface使用",
+		strings.SplitN(analysisTool1.RuleViolations()[4].String(), ": ", 2)[1])
+
+	// 配置错误时分析没有完成
+	assert.NotNil(t, analysisTestdata("rules", Config{Rules: rules, IncludeTypes: []string{"("}}).Err())
+	assert.NotNil(t, AnalysisCode(Config{CodeDir: testdataPath + "/rules", GopathDir: testdataPath + "/nonexistent", Rules: rules}).Err())
+
+	_, err = LoadRules(testdataPath + "/rules/missing.json")
+	assert.NotNil(t, err)
+
+}
+
+/**
+ * 测试包的度量: 类型, 方法和字段的数量, 传入和传出耦合, 不稳定性, 抽象程度和距离; 各种输出格式
+ */
+func Test_metrics(t *testing.T) {
+
+	analysisTool1 := analysisTestdata("rules", Config{})
+
+	metrics := analysisTool1.Metrics()
+	assert.Equal(t, 4, len(metrics))
+
+	services := metrics[3]
+	assert.Equal(t, "github.com/maobuji/go-package-plantuml/testdata/rules/internal/services", services.Package)
+	assert.Equal(t, 2, services.Types)
+	assert.Equal(t, 1, services.Interfaces)
+	assert.Equal(t, 1, services.Methods)
+	assert.Equal(t, 1, services.Fields)
+	// Handler同时依赖UserService和Store, 只计算一次
+	assert.Equal(t, 1, services.Afferent)
+	assert.Equal(t, 0, services.Efferent)
+	assert.Equal(t, 0.0, services.Instability)
+	assert.Equal(t, 0.5, services.Abstractness)
+	assert.Equal(t, 0.5, services.Distance)
+
+	handlers := metrics[1]
+	assert.Equal(t, 2, handlers.Efferent)
+	assert.Equal(t, 1.0, handlers.Instability)
+	assert.Equal(t, 0.0, handlers.Distance)
+
+	csv := MetricsCSV(metrics)
+	assert.True(t, strings.HasPrefix(csv, "package,types,interfaces,methods,fields,afferent,efferent,instability,abstractness,distance\n"))
+	assert.True(t, strings.Contains(csv, "testdata/rules/internal/services,2,1,1,1,1,0,0.00,0.50,0.50\n"))
+
+	assert.True(t, strings.Contains(MetricsJSON(metrics), "\"abstractness\": 0.5,"))
+
+	table := MetricsTable(metrics)
+	assert.True(t, strings.HasPrefix(table, "package "))
+	assert.Equal(t, 5, strings.Count(table, "\n"))
+
+}
+
+/**
+ * 测试聚焦类型: 按照深度和方向显示相关的类型, 边界类型显示为stub; 找不到类型或者类型不唯一时失败; 各种输出格式
+ */
+func Test_focus(t *testing.T) {
+
+	// 显示的类型, 边界类型后面加上*
+	shown := func(analysisTool1 *analysisTool) []string {
+		return typeNames(analysisTool1, func(typeMeta1 typeMeta) string {
+			if analysisTool1.isStub(typeMeta1) {
+				return typeMetaName(typeMeta1) + "*"
+			} else if analysisTool1.showType(typeMeta1) {
+				return typeMetaName(typeMeta1)
+			}
+			return ""
+		})
+	}
+
+	analysisTool1 := analysisTestdata("focus", Config{Focus: "Middle", FocusDepth: 1})
+	assert.Equal(t, []string{"Caller*", "Root", "Middle", "Leaf", "End*", "Namer"}, shown(analysisTool1))
+
+	uml := analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, " class End <<stub>> {\n} "))
+	assert.True(t, strings.Contains(uml, "focus.Leaf o-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\focus.End : end\n"))
+	// 边界类型之间的关系不显示
+	assert.False(t, strings.Contains(uml, "focus.End o--"))
+	assert.True(t, strings.Contains(uml, "focus.Namer <|- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\focus.Middle : <<pointer>>\n"))
+
+	assert.Equal(t, []string{"Middle", "Leaf", "End*", "Namer"}, shown(analysisTestdata("focus", Config{Focus: "github.com/maobuji/go-package-plantuml/testdata/focus.Middle", FocusDepth: 1, FocusDirection: FocusOut})))
+	assert.Equal(t, []string{"Caller*", "Root", "Middle"}, shown(analysisTestdata("focus", Config{Focus: "Middle", FocusDepth: 1, FocusDirection: FocusIn})))
+	assert.Equal(t, []string{"Caller", "Root", "Middle", "Leaf", "End", "Namer"}, shown(analysisTestdata("focus", Config{Focus: "Middle", FocusDepth: 2, FocusDirection: FocusBoth})))
+
+	// 找不到类型或者类型不唯一时分析失败
+	assert.NotNil(t, analysisTestdata("focus", Config{Focus: "Missing", FocusDepth: 1}).Err())
+	assert.Nil(t, analysisTestdata("focus", Config{Focus: "Middle", FocusDepth: 1}).Err())
+	assert.NotNil(t, analysisTestdata("uml", Config{Focus: "SA"}).Err())
+
+	assert.True(t, strings.Contains(analysisTestdata("focus", Config{Format: FormatMermaid, Focus: "Middle", FocusDepth: 1}).UML(), "[\"End\"] {\n    <<stub>>\n  }\n"))
+	assert.True(t, strings.Contains(analysisTestdata("focus", Config{Format: FormatDot, Focus: "Middle", FocusDepth: 1}).UML(), "<i>«stub»</i><br/><b>End</b>"))
+	assert.True(t, strings.Contains(analysisTestdata("focus", Config{Format: FormatSVG, Focus: "Middle", FocusDepth: 1}).UML(), ">«stub»</text>"))
+
+}
+
+/**
+ * 测试文件, 目录, 包和类型的过滤: 文件的过滤不解析, 包和类型的过滤只影响显示; 排除的类型显示为边界类型; 正则表达式错误时不分析
+ */
+func Test_filters(t *testing.T) {
+
+	names := func(analysisTool1 *analysisTool, shownOnly bool) []string {
+		return typeNames(analysisTool1, func(typeMeta1 typeMeta) string {
+			if !shownOnly || analysisTool1.showType(typeMeta1) {
+				return typeMetaName(typeMeta1)
+			}
+			return ""
+		})
+	}
+
+	assert.Equal(t, []string{"MockClient", "GeneratedModel", "Scratch", "Service", "internalHelper", "Artifact", "Repo", "Client"},
+		names(analysisTestdata("filter", Config{}), false))
+
+	// 文件的排除规则和.gitignore中的文件不解析
+	analysisTool1 := analysisTestdata("filter", Config{
+		ExcludeFiles: []string{"*_gen.go", "app/mock_*.go"},
+		UseGitignore: true,
+	})
+	assert.Equal(t, []string{"Service", "internalHelper", "Repo", "Client"}, names(analysisTool1, false))
+
+	// IgnoreDirs按照路径的每一段匹配
+	assert.True(t, inSomeDir("/a/foo/x.go", []string{"/a/foo"}))
+	assert.True(t, inSomeDir("/a/foo", []string{"/a/foo/"}))
+	assert.False(t, inSomeDir("/a/foobar/x.go", []string{"/a/foo"}))
+	assert.Equal(t, []string{"Artifact", "Repo"}, names(analysisTestdata("filter", Config{IgnoreDirs: []string{testdataPath + "/filter/app"}}), false))
+	assert.Equal(t, 8, len(names(analysisTestdata("filter", Config{IgnoreDirs: []string{testdataPath + "/filter/ap"}}), false)))
+
+	analysisTool1 = analysisTestdata("filter", Config{IncludeFiles: []string{"store/**"}})
+	assert.Equal(t, []string{"Repo"}, names(analysisTool1, false))
+
+	// 包和类型名的排除规则只影响显示
+	analysisTool1 = analysisTestdata("filter", Config{
+		ExcludePackages: []string{"store", "build"},
+		ExcludeTypes: []string{"^internal", "Mock"},
+	})
+	assert.Equal(t, 8, len(names(analysisTool1, false)))
+	assert.Equal(t, []string{"GeneratedModel", "Scratch", "Service", "Client"}, names(analysisTool1, true))
+
+	analysisTool1 = analysisTestdata("filter", Config{
+		IncludePackages: []string{"app"},
+		IncludeTypes: []string{"^S"},
+	})
+	assert.Equal(t, []string{"Scratch", "Service"}, names(analysisTool1, true))
+
+	// 被使用的排除类型显示为灰色的边界类型, 使用了包含的类型的排除类型不显示
+	analysisTool1 = analysisTestdata("filter", Config{
+		ExcludePackages: []string{"store"},
+		ExcludeTypes: []string{"^internal", "^Client$"},
+		ShowExcludedStubs: true,
+	})
+	assert.Equal(t, []string{"MockClient", "GeneratedModel", "Scratch", "Service", "Artifact", "Repo", "Client"}, names(analysisTool1, true))
+	assert.True(t, analysisTool1.isStub(analysisTool1.findStruct("github.com/maobuji/go-package-plantuml/testdata/filter/store", "Repo")))
+	uml := analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, " class Repo <<stub>> #eeeeee {\n} "))
+	assert.True(t, strings.Contains(uml, " interface Client <<stub>> #eeeeee {\n} "))
+	assert.True(t, strings.Contains(uml, "filter\\\\app.Client <|- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\filter\\\\app.MockClient : <<pointer>>\n"))
+
+	config := Config{ExcludePackages: []string{"store"}, ShowExcludedStubs: true}
+	config.Format = FormatSVG
+	assert.True(t, strings.Contains(analysisTestdata("filter", config).UML(), "<rect class=\"node excluded\""))
+	config.Format = FormatMermaid
+	assert.True(t, strings.Contains(analysisTestdata("filter", config).UML(), "style github_com_maobuji_go_package_plantuml_testdata_filter_store_Repo fill:#eeeeee"))
+
+	analysisTool1 = analysisTestdata("filter", Config{View: ViewPackages, ExcludePackages: []string{"store"}})
+	assert.False(t, strings.Contains(analysisTool1.UML(), "store"))
+
+	// 正则表达式错误时不分析
+	assert.Equal(t, 0, len(analysisTestdata("filter", Config{ExcludeTypes: []string{"("}}).structMetas))
+
+}
+
+/**
+ * 测试生成的代码: 默认和其他代码一样显示, 可以跳过, 单独分组或者每个包合并为一个节点; 各种输出格式
+ */
+func Test_generated(t *testing.T) {
+
+	names := func(analysisTool1 *analysisTool) []string {
+		return typeNames(analysisTool1, typeMetaName)
+	}
+
+	analysisTool1 := analysisTestdata("generated", Config{})
+	assert.Equal(t, []string{"MockStore", "Service", "User", "Address", "Store", "UserServiceClient"}, names(analysisTool1))
+	assert.False(t, strings.Contains(analysisTool1.UML(), "<<generated>>"))
+
+	assert.Equal(t, []string{"Service", "Store"}, names(analysisTestdata("generated", Config{Generated: GeneratedSkip})))
+
+	analysisTool1 = analysisTestdata("generated", Config{Generated: GeneratedSeparate})
+	assert.Equal(t, 6, len(names(analysisTool1)))
+	uml := analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, "  BackgroundColor<<generated>> #f0f0f0\n"))
+	assert.True(t, strings.Contains(uml, " class User <<generated>> {\n"))
+	assert.True(t, strings.Contains(uml, " interface UserServiceClient <<generated>>  {\n"))
+	assert.True(t, strings.Contains(uml, " class Service {\n"))
+	// 生成的类型放在包的子namespace中, 关系指向子namespace中的类型
+	assert.True(t, strings.Contains(uml, "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generated\\\\app.generated #f0f0f0 {\n class MockStore <<generated>> {\n"))
+	assert.True(t, strings.Contains(uml, "namespace github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generated\\\\app {\n class Service {\n"))
+	assert.True(t, strings.Contains(uml, "app.Service o-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generated\\\\pb.generated.User : user\n"))
+	assert.True(t, strings.Contains(uml, "app.Store <|- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generated\\\\app.generated.MockStore : <<pointer>>\n"))
+
+	assert.True(t, strings.Contains(analysisTestdata("generated", Config{Format: FormatMermaid, Generated: GeneratedSeparate}).UML(),
+		"namespace github_com_maobuji_go_package_plantuml_testdata_generated_pb__generated {\n"))
+	assert.True(t, strings.Contains(analysisTestdata("generated", Config{Format: FormatDot, Generated: GeneratedSeparate}).UML(),
+		"  subgraph cluster_github_com_maobuji_go_package_plantuml_testdata_generated_pb_generated {\n    label=\"github.com/maobuji/go-package-plantuml/testdata/generated/pb «generated»\";\n"))
+	assert.True(t, strings.Contains(analysisTestdata("generated", Config{Format: FormatSVG, Generated: GeneratedSeparate}).UML(), ">github.com/maobuji/go-package-plantuml/testdata/generated/pb «generated»</text>"))
+
+	// 每个包中生成的类型合并为一个节点, 节点内部的关系不显示
+	analysisTool1 = analysisTestdata("generated", Config{Generated: GeneratedCollapse})
+	assert.Equal(t, []string{"Service", "generated", "generated", "Store"}, names(analysisTool1))
+	assert.Equal(t, []string{"User", "Address", "UserServiceClient"}, analysisTool1.structMetas[2].CollapsedTypes)
+	uml = analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, " class generated <<generated>> {\n  User\n  Address\n  UserServiceClient\n} "))
+	assert.True(t, strings.Contains(uml, "app.Service o-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generated\\\\pb.generated.generated : client\n"))
+	assert.True(t, strings.Contains(uml, "app.Service o-- github.com\\\\maobuji\\\\go_package_plantuml\\\\testdata\\\\generated\\\\pb.generated.generated : user\n"))
+	assert.False(t, strings.Contains(uml, "pb.generated.generated o--"))
+
+}
+
+/**
+ * 测试目标平台: 按照GOOS, GOARCH和构建标签选择文件; 类型的构建约束显示为构造型; 文件名中的平台
+ */
+func Test_platform(t *testing.T) {
+
+	fields := func(analysisTool1 *analysisTool) map[string][]string {
+		result := map[string][]string{}
+		for _, structMeta1 := range analysisTool1.structMetas {
+			result[structMeta1.Name] = []string{}
+			for _, field := range structMeta1.Fields {
+				result[structMeta1.Name] = append(result[structMeta1.Name], field.Name)
+			}
+		}
+		return result
+	}
+
+	assert.Equal(t, map[string][]string{
+		"Server": {"conn", "poller"},
+		"Conn":   {"fd"},
+		"Poller": {"epfd"},
+	}, fields(analysisTestdata("platform", Config{GOOS: "linux", GOARCH: "amd64"})))
+
+	assert.Equal(t, map[string][]string{
+		"Server": {"conn", "poller"},
+		"Conn":   {"handle"},
+	}, fields(analysisTestdata("platform", Config{GOOS: "windows", GOARCH: "amd64"})))
+
+	assert.Equal(t, map[string][]string{
+		"Server": {"conn", "poller"},
+		"Conn":   {"fd"},
+		"Poller": {"epfd"},
+		"Tracer": {"server"},
+	}, fields(analysisTestdata("platform", Config{GOOS: "linux", GOARCH: "amd64", BuildTags: []string{"debug"}})))
+
+	uml := analysisTestdata("platform", Config{GOOS: "linux", GOARCH: "amd64", BuildTags: []string{"debug"}, ShowPlatform: true}).UML()
+	assert.True(t, strings.Contains(uml, " class Conn <<linux>> {\n"))
+	assert.True(t, strings.Contains(uml, " class Poller <<linux || darwin>> {\n"))
+	assert.True(t, strings.Contains(uml, " class Tracer <<debug>> {\n"))
+	assert.True(t, strings.Contains(uml, " class Server {\n"))
+	assert.False(t, strings.Contains(analysisTestdata("platform", Config{GOOS: "linux", GOARCH: "amd64"}).UML(), "<<linux>>"))
+
+	assert.True(t, strings.Contains(analysisTestdata("platform", Config{Format: FormatMermaid, GOOS: "windows", GOARCH: "amd64", ShowPlatform: true}).UML(), "[\"Conn «windows»\"]"))
+	assert.True(t, strings.Contains(analysisTestdata("platform", Config{Format: FormatDot, GOOS: "windows", GOARCH: "amd64", ShowPlatform: true}).UML(), "<i>«windows»</i>"))
+	assert.True(t, strings.Contains(analysisTestdata("platform", Config{Format: FormatSVG, GOOS: "windows", GOARCH: "amd64", ShowPlatform: true}).UML(), ">«windows»</text>"))
+
+	analysisTool1 := analysisTestdata("platform", Config{GOOS: "linux", GOARCH: "amd64"})
+	assert.Equal(t, "linux && amd64", analysisTool1.fileConstraint("/src/conn_linux_amd64.go"))
+	assert.Equal(t, "arm64", analysisTool1.fileConstraint("/src/conn_arm64.go"))
+	assert.Equal(t, "", analysisTool1.fileConstraint("/src/linux.go"))
+	assert.Equal(t, "linux", analysisTool1.fileConstraint("/src/conn_linux_test.go"))
+	assert.Equal(t, "", analysisTool1.fileConstraint("/src/linux_test.go"))
+
+	// 测试文件的文件名后缀去掉 _test 后判断
+	assert.True(t, strings.Contains(analysisTestdata("platform", Config{GOOS: "linux", GOARCH: "amd64", Tests: true, ShowPlatform: true}).UML(),
+		" class fakeConn <<test>> <<linux>> {\n"))
+	assert.Equal(t, map[string][]string{
+		"Server": {"conn", "poller"},
+		"Conn":   {"handle"},
+	}, fields(analysisTestdata("platform", Config{GOOS: "windows", GOARCH: "amd64", Tests: true})))
+}
+
+/**
+ * 测试测试代码: 测试文件中的类型和测试函数, 外部测试包; 测试文件到使用的类型的依赖; 测试中的类型不计入度量; 各种输出格式
+ */
+func Test_tests(t *testing.T) {
+
+	names := func(analysisTool1 *analysisTool) []string {
+		return typeNames(analysisTool1, func(typeMeta1 typeMeta) string {
+			return typeMetaPackagePath(typeMeta1) + "." + typeMetaName(typeMeta1)
+		})
+	}
+
+	storePath := "github.com/maobuji/go-package-plantuml/testdata/tests/store"
+
+	// latest.go不是测试文件
+	analysisTool1 := analysisTestdata("tests", Config{})
+	assert.Equal(t, []string{storePath + ".Latest", storePath + ".Cache", storePath + ".Store"}, names(analysisTool1))
+	assert.False(t, strings.Contains(analysisTool1.UML(), "<<test>>"))
+
+	analysisTool1 = analysisTestdata("tests", Config{Tests: true})
+	assert.Equal(t, []string{storePath + ".fakeStore", storePath + "_test.memoryStore", storePath + ".Latest", storePath + ".Cache",
+		storePath + ".cache_test", storePath + "_test.example_test", storePath + ".Store"},
+		names(analysisTool1))
+
+	assert.Equal(t, 0, len(analysisTool1.findStruct(storePath, "Latest").Methods))
+	testFuncs := []string{}
+	for _, method := range analysisTool1.findStruct(storePath, "cache_test").Methods {
+		testFuncs = append(testFuncs, method.Name + "(" + method.Params + ")")
+	}
+	assert.Equal(t, []string{"TestCacheGet(t *testing.T)", "TestLatest(t *testing.T)"}, testFuncs)
+
+	storeUML := packagePathToUML(storePath)
+	storeTestUML := packagePathToUML(storePath + "_test")
+
+	uml := analysisTool1.UML()
+	assert.True(t, strings.Contains(uml, "  BackgroundColor<<test>> #eaf5ea\n"))
+	assert.True(t, strings.Contains(uml, " class fakeStore <<test>> {\n"))
+	assert.True(t, strings.Contains(uml, " class cache_test <<test>> {\n"))
+	assert.True(t, strings.Contains(uml, " class Cache {\n"))
+	// 测试中的类型放在包的子namespace中, 外部测试包也一样
+	assert.True(t, strings.Contains(uml, "namespace " + storeUML + ".test #eaf5ea {\n class fakeStore <<test>> {\n"))
+	assert.True(t, strings.Contains(uml, "namespace " + storeUML + ".test #eaf5ea {\n class cache_test <<test>> {\n"))
+	assert.True(t, strings.Contains(uml, "namespace " + storeUML + " {\n class Cache {\n"))
+	assert.True(t, strings.Contains(uml, "namespace " + storeTestUML + ".test #eaf5ea {\n class example_test <<test>> {\n"))
+	assert.True(t, strings.Contains(uml, storeUML + ".test.cache_test ..> " + storeUML + ".Cache : TestCacheGet <<test>>\n"))
+	assert.True(t, strings.Contains(uml, storeUML + ".test.cache_test ..> " + storeUML + ".test.fakeStore : TestCacheGet <<test>>\n"))
+	assert.True(t, strings.Contains(uml, storeUML + ".test.cache_test ..> " + storeUML + ".Latest : TestLatest <<test>>\n"))
+	assert.False(t, strings.Contains(uml, storeUML + ".test.cache_test ..> " + storeUML + ".Store"))
+	assert.True(t, strings.Contains(uml, storeTestUML + ".test.example_test ..> " + storeUML + ".Store : ExampleCache <<test>>\n"))
+	assert.True(t, strings.Contains(uml, storeTestUML + ".test.example_test ..> " + storeUML + ".Cache : ExampleCache <<test>>\n"))
+
+	// 测试中的类型不计入度量
+	for _, metrics := range analysisTool1.Metrics() {
+		assert.Equal(t, storePath, metrics.Package)
+		assert.Equal(t, 3, metrics.Types)
+	}
+
+	assert.True(t, strings.Contains(analysisTestdata("tests", Config{Format: FormatMermaid, Tests: true}).UML(),
+		"namespace github_com_maobuji_go_package_plantuml_testdata_tests_store__test {\n"))
+	assert.True(t, strings.Contains(analysisTestdata("tests", Config{Format: FormatDot, Tests: true}).UML(),
+		"  subgraph cluster_github_com_maobuji_go_package_plantuml_testdata_tests_store_test {\n    label=\"github.com/maobuji/go-package-plantuml/testdata/tests/store «test»\";\n"))
+	assert.True(t, strings.Contains(analysisTestdata("tests", Config{Format: FormatSVG, Tests: true}).UML(), ">github.com/maobuji/go-package-plantuml/testdata/tests/store_test «test»</text>"))
+}
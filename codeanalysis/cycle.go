@@ -0,0 +1,287 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"go/token"
+	"sort"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// 循环依赖的种类
+const (
+	cyclePackages = "packages"
+	cycleTypes    = "types"
+)
+
+// 一个强连通分量中的循环依赖
+type dependencyCycle struct {
+	// packages 或 types
+	Kind string
+	// 循环中的包路径或者 包路径.类型名
+	Nodes []string
+	// 循环中的边
+	Edges []*cycleEdge
+	// 类型所在的包的数量, 包的循环依赖为0
+	PackageCount int
+}
+
+// 循环依赖中的一条边
+type cycleEdge struct {
+	Source string
+	Target string
+	// 形成依赖的源码位置和说明, 例如 /appdev/a/a.go:12 字段 b
+	Reasons []string
+}
+
+/**
+ * 使用Tarjan算法计算有向图的强连通分量, 只返回包含两个及以上节点的分量, 分量中的节点按照编号排序
+ */
+func stronglyConnectedComponents(count int, successors [][]int) [][]int {
+
+	index := 0
+	indexes := make([]int, count)
+	lowLinks := make([]int, count)
+	onStack := make([]bool, count)
+	stack := []int{}
+	components := [][]int{}
+
+	for node := range indexes {
+		indexes[node] = -1
+	}
+
+	var connect func(node int)
+	connect = func(node int) {
+
+		indexes[node] = index
+		lowLinks[node] = index
+		index++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, next := range successors[node] {
+			if indexes[next] == -1 {
+				connect(next)
+				if lowLinks[next] < lowLinks[node] {
+					lowLinks[node] = lowLinks[next]
+				}
+			} else if onStack[next] && indexes[next] < lowLinks[node] {
+				lowLinks[node] = indexes[next]
+			}
+		}
+
+		if lowLinks[node] != indexes[node] {
+			return
+		}
+
+		component := []int{}
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == node {
+				break
+			}
+		}
+
+		if len(component) > 1 {
+			sort.Ints(component)
+			components = append(components, component)
+		}
+	}
+
+	for node := 0; node < count; node++ {
+		if indexes[node] == -1 {
+			connect(node)
+		}
+	}
+
+	return components
+}
+
+/**
+ * 查找包的import关系和类型之间的关系中的循环依赖, 循环中的边标记为cycle
+ */
+func (this *analysisTool) detectCycles() {
+
+	this.detectPackageCycles()
+	this.detectTypeCycles()
+
+	if len(this.cycles) > 0 {
+		log.Warnf("发现%d个循环依赖", len(this.cycles))
+	}
+}
+
+func (this *analysisTool) detectPackageCycles() {
+
+	nodeIndexes := map[*packageNode]int{}
+	for index, node := range this.packageNodes {
+		nodeIndexes[node] = index
+	}
+
+	successors := make([][]int, len(this.packageNodes))
+	for _, edge := range this.packageEdges {
+		source := nodeIndexes[edge.source]
+		successors[source] = append(successors[source], nodeIndexes[edge.target])
+	}
+
+	for _, component := range stronglyConnectedComponents(len(this.packageNodes), successors) {
+
+		cycle := &dependencyCycle{Kind: cyclePackages}
+		inComponent := map[*packageNode]bool{}
+
+		for _, index := range component {
+			node := this.packageNodes[index]
+			inComponent[node] = true
+			cycle.Nodes = append(cycle.Nodes, node.Name)
+		}
+
+		for _, edge := range this.packageEdges {
+			if !inComponent[edge.source] || !inComponent[edge.target] {
+				continue
+			}
+			edge.cycle = true
+			reasons := []string{}
+			for _, position := range edge.positions {
+				reasons = append(reasons, positionToString(position))
+			}
+			cycle.Edges = append(cycle.Edges, &cycleEdge{
+				Source:  edge.source.Name,
+				Target:  edge.target.Name,
+				Reasons: reasons,
+			})
+		}
+
+		this.cycles = append(this.cycles, cycle)
+	}
+}
+
+func (this *analysisTool) detectTypeCycles() {
+
+	nodes := []typeMeta{}
+	for _, structMeta1 := range this.structMetas {
+		nodes = append(nodes, structMeta1)
+	}
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		nodes = append(nodes, interfaceMeta1)
+	}
+
+	nodeIndexes := map[typeMeta]int{}
+	for index, node := range nodes {
+		nodeIndexes[node] = index
+	}
+
+	successors := make([][]int, len(nodes))
+	for _, d := range this.dependencyRelations {
+		source, sourceFound := nodeIndexes[d.source]
+		target, targetFound := nodeIndexes[d.target]
+		if sourceFound && targetFound && source != target {
+			successors[source] = append(successors[source], target)
+		}
+	}
+
+	for _, component := range stronglyConnectedComponents(len(nodes), successors) {
+
+		cycle := &dependencyCycle{Kind: cycleTypes}
+		inComponent := map[typeMeta]bool{}
+		packagePaths := []string{}
+
+		for _, index := range component {
+			node := nodes[index]
+			inComponent[node] = true
+			cycle.Nodes = append(cycle.Nodes, typeMetaPackagePath(node)+"."+typeMetaName(node))
+			if !sliceContains(packagePaths, typeMetaPackagePath(node)) {
+				packagePaths = append(packagePaths, typeMetaPackagePath(node))
+			}
+		}
+		cycle.PackageCount = len(packagePaths)
+
+		for _, d := range this.dependencyRelations {
+			if d.source == d.target || !inComponent[d.source] || !inComponent[d.target] {
+				continue
+			}
+			d.cycle = true
+			source := typeMetaPackagePath(d.source) + "." + typeMetaName(d.source)
+			target := typeMetaPackagePath(d.target) + "." + typeMetaName(d.target)
+			reason := positionToString(d.position) + " " + relationReason(d)
+
+			// 同一对类型之间的多个关系合并到一条边中
+			var edge *cycleEdge
+			for _, existEdge := range cycle.Edges {
+				if existEdge.Source == source && existEdge.Target == target {
+					edge = existEdge
+				}
+			}
+			if edge == nil {
+				edge = &cycleEdge{Source: source, Target: target}
+				cycle.Edges = append(cycle.Edges, edge)
+			}
+			edge.Reasons = append(edge.Reasons, reason)
+		}
+
+		this.cycles = append(this.cycles, cycle)
+	}
+}
+
+/**
+ * 关系的来源, 例如 字段 items, 方法 Save, 嵌入
+ */
+func relationReason(d *DependencyRelation) string {
+	switch {
+	case d.label != "":
+		return "字段 " + d.label
+	case d.method != "":
+		return "方法 " + d.method
+	case d.kind == relationEmbeds || d.kind == relationExtends:
+		return "嵌入"
+	}
+	return "底层类型"
+}
+
+func positionToString(position token.Position) string {
+	if !position.IsValid() {
+		return "未知位置"
+	}
+	return fmt.Sprintf("%s:%d", position.Filename, position.Line)
+}
+
+/**
+ * 循环依赖报告, 每个循环列出其中的节点, 以及形成每条边的文件和字段
+ */
+func (this *analysisTool) CycleReport() string {
+
+	if len(this.cycles) == 0 {
+		return ""
+	}
+
+	report := fmt.Sprintf("发现%d个循环依赖\n", len(this.cycles))
+
+	for index, cycle := range this.cycles {
+
+		if cycle.Kind == cyclePackages {
+			report += fmt.Sprintf("\n%d. 包循环依赖, %d个包: %s\n", index+1, len(cycle.Nodes), strings.Join(cycle.Nodes, ", "))
+		} else if cycle.PackageCount > 1 {
+			report += fmt.Sprintf("\n%d. 类型循环依赖, 跨%d个包: %s\n", index+1, cycle.PackageCount, strings.Join(cycle.Nodes, ", "))
+		} else {
+			report += fmt.Sprintf("\n%d. 类型循环依赖: %s\n", index+1, strings.Join(cycle.Nodes, ", "))
+		}
+
+		for _, edge := range cycle.Edges {
+			report += "  " + edge.Source + " -> " + edge.Target + "\n"
+			for _, reason := range edge.Reasons {
+				report += "    " + reason + "\n"
+			}
+		}
+	}
+
+	return report
+}
+
+/**
+ * 是否用红色画出循环依赖中的边
+ */
+func (this *analysisTool) highlightCycle(cycle bool) bool {
+	return cycle && this.config.HighlightCycles
+}
@@ -0,0 +1,101 @@
+package codeanalysis
+
+import (
+	"go/ast"
+)
+
+// UML图的详细程度
+const (
+	// 所有类型和成员
+	DetailFull = "full"
+	// 只有导出的类型和成员
+	DetailPublicAPI = "public-api"
+	// 只有方法, 不显示字段
+	DetailSignatures = "signatures"
+	// 只有类型名, 不显示成员
+	DetailNamesOnly = "names-only"
+)
+
+var details = []string{DetailFull, DetailPublicAPI, DetailSignatures, DetailNamesOnly}
+
+/**
+ * 根据标识符的首字母大小写得到UML的可见性, 导出的为+, 未导出的为-
+ */
+func visibilityToUML(name string) string {
+	if ast.IsExported(name) {
+		return "+"
+	}
+	return "-"
+}
+
+func typeMetaName(typeMeta1 typeMeta) string {
+	switch meta := typeMeta1.(type) {
+	case *structMeta:
+		return meta.Name
+	case *interfaceMeta:
+		return meta.Name
+	case *typeAliasMeta:
+		return meta.Name
+	case *interfaceImpl:
+		return meta.Name
+	}
+	return ""
+}
+
+func typeMetaFilePath(typeMeta1 typeMeta) string {
+	switch meta := typeMeta1.(type) {
+	case *structMeta:
+		return meta.FilePath
+	case *interfaceMeta:
+		return meta.FilePath
+	case *typeAliasMeta:
+		return meta.FilePath
+	case *interfaceImpl:
+		return meta.FilePath
+	}
+	return ""
+}
+
+func typeMetaPackagePath(typeMeta1 typeMeta) string {
+	switch meta := typeMeta1.(type) {
+	case *structMeta:
+		return meta.PackagePath
+	case *interfaceMeta:
+		return meta.PackagePath
+	case *typeAliasMeta:
+		return meta.PackagePath
+	case *interfaceImpl:
+		return meta.PackagePath
+	}
+	return ""
+}
+
+/**
+ * public-api时只显示导出的类型, 设置了Focus时只显示Focus范围内的类型, 排除的类型只做为边界类型显示
+ */
+func (this *analysisTool) showType(typeMeta1 typeMeta) bool {
+	if this.isExcluded(typeMeta1) {
+		return true
+	}
+	return this.inFocus(typeMeta1) && this.typeIncluded(typeMeta1) && this.detailShowsType(typeMeta1)
+}
+
+func (this *analysisTool) showField(name string) bool {
+	switch this.config.Detail {
+	case DetailSignatures, DetailNamesOnly:
+		return false
+	case DetailPublicAPI:
+		return ast.IsExported(name)
+	}
+	return true
+}
+
+func (this *analysisTool) showMethod(name string) bool {
+	switch this.config.Detail {
+	case DetailNamesOnly:
+		return false
+	case DetailPublicAPI:
+		return ast.IsExported(name)
+	}
+	return true
+}
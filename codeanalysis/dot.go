@@ -0,0 +1,302 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Graphviz DOT格式的类图, 包画成cluster子图, 类型画成HTML表格
+type dotRenderer struct {
+	tool *analysisTool
+}
+
+// 关系的边的样式, 自底向上布局, 边从source指向target
+var dotEdgeStyles = map[string]string{
+	relationComposition: "dir=both, arrowtail=diamond, arrowhead=none",
+	relationAggregation: "dir=both, arrowtail=odiamond, arrowhead=none",
+	relationAssociation: "arrowhead=vee",
+	relationDependency:  "style=dashed, arrowhead=vee",
+	relationExtends:     "arrowhead=empty",
+}
+
+func (this *dotRenderer) render() string {
+
+	if this.tool.config.View == ViewPackages {
+		return this.renderPackages()
+	}
+
+	nodes := []typeMeta{}
+
+	for _, structMeta1 := range this.tool.structMetas {
+		if this.tool.showType(structMeta1) {
+			nodes = append(nodes, structMeta1)
+		}
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if this.tool.showType(interfaceMeta1) {
+			nodes = append(nodes, interfaceMeta1)
+		}
+	}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		nodes = append(nodes, typeAliasMeta1)
+	}
+
+	result := "digraph G {\n" +
+		"  rankdir=BT;\n" +
+		"  node [shape=plain, fontname=\"Helvetica\", fontsize=10];\n" +
+		"  edge [fontname=\"Helvetica\", fontsize=9];\n"
+
+	// 同一个包中的类型放在一个cluster中, 生成的类型和测试中的类型放在单独的有背景色的cluster中
+	for _, group := range this.tool.groupNodes(nodes) {
+		if group.Layer != "" {
+			result += "  subgraph cluster_" + safeId(group.PackagePath) + "_" + group.Layer + " {\n"
+			result += "    label=" + dotQuote(group.PackagePath+" «"+group.Layer+"»") + ";\n"
+			result += "    style=\"rounded,filled\";\n"
+			result += "    fillcolor=\"" + layerColors[group.Layer][0] + "\";\n"
+		} else {
+			result += "  subgraph cluster_" + safeId(group.PackagePath) + " {\n"
+			result += "    label=" + dotQuote(group.PackagePath) + ";\n"
+			result += "    style=rounded;\n"
+		}
+		result += "    color=gray;\n"
+		for _, node := range group.Nodes {
+			result += "    " + this.nodeId(node) + " [label=<" + this.nodeLabel(node) + ">];\n"
+		}
+		result += "  }\n"
+	}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		if target := this.tool.typeAliasTarget(typeAliasMeta1); target != nil {
+			result += "  " + this.nodeId(typeAliasMeta1) + " -> " + this.nodeId(target) + " [style=dashed, arrowhead=vee, label=\"alias\"];\n"
+		}
+	}
+
+	for _, d := range this.tool.shownRelations() {
+		result += "  " + this.relationToDot(d) + ";\n"
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if !this.tool.showType(interfaceMeta1) {
+			continue
+		}
+		for _, interfaceImpl1 := range this.tool.findInterfaceImpls(interfaceMeta1) {
+			if this.tool.showRelation(interfaceImpl1, interfaceMeta1) {
+				result += "  " + this.implToDot(interfaceMeta1, interfaceImpl1) + ";\n"
+			}
+		}
+	}
+
+	return result + "}\n"
+}
+
+func (this *dotRenderer) nodeId(node typeMeta) string {
+	return dotQuote(typeMetaPackagePath(node) + "." + typeMetaName(node))
+}
+
+/**
+ * DOT中的字符串, 双引号和反斜杠需要转义
+ */
+func dotQuote(text string) string {
+	return "\"" + strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(text) + "\""
+}
+
+/**
+ * HTML标签中的文字, 例如 map[string]*T 中没有需要转义的字符, Box<T> 转义为 Box&lt;T&gt;
+ */
+func htmlEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\"", "&quot;").Replace(text)
+}
+
+/**
+ * 节点的HTML表格, 第一行为类型名, 后面每组成员一行, 类型的种类显示在类型名上面, 例如 «interface»
+ */
+func (this *dotRenderer) nodeLabel(node typeMeta) string {
+
+	name := typeMetaName(node)
+	annotation := ""
+
+	switch meta := node.(type) {
+	case *structMeta:
+		name += typeParamsToUML(meta.TypeParams)
+		switch {
+		case len(meta.EnumValues) > 0:
+			annotation = "enumeration"
+		case meta.UnderlyingType != "":
+			annotation = meta.UnderlyingType
+		}
+	case *interfaceMeta:
+		name += typeParamsToUML(meta.TypeParams)
+		annotation = "interface"
+		if len(meta.TypeUnion) > 0 {
+			annotation = strings.Join(meta.TypeUnion, "; ")
+		}
+	case *typeAliasMeta:
+		annotation = "alias"
+		if this.tool.typeAliasTarget(meta) == nil {
+			annotation = "alias " + meta.targetTypeName
+		}
+	}
+
+	if layer := this.tool.layerOf(node); layer != "" {
+		annotation = strings.TrimSuffix(layer+", "+annotation, ", ")
+	}
+
+	if platform := this.tool.platformOf(node); platform != "" {
+		annotation = strings.TrimPrefix(annotation+", "+platform, ", ")
+	}
+
+	if this.tool.isStub(node) {
+		annotation = "stub"
+	}
+
+	header := "<b>" + htmlEscape(name) + "</b>"
+	if annotation != "" {
+		header = "<i>«" + htmlEscape(annotation) + "»</i><br/>" + header
+	}
+
+	label := "<table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">"
+	if this.tool.isExcluded(node) {
+		label = "<table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\" bgcolor=\"#eeeeee\" color=\"#999999\">"
+	}
+	label += "<tr><td>" + header + "</td></tr>"
+
+	if _, ok := node.(*typeAliasMeta); !ok {
+		label += this.membersToDot(node)
+	}
+
+	return label + "</table>"
+}
+
+/**
+ * 节点的成员, 每组一行, 嵌入类型提升的成员所在的行以 «embedded X» 开头
+ */
+func (this *dotRenderer) membersToDot(node typeMeta) string {
+
+	groups, hidden := this.tool.classMembers(node)
+
+	result := ""
+
+	for index, group := range groups {
+
+		lines := []string{}
+		if group.Title != "" {
+			lines = append(lines, "<i>«"+htmlEscape(group.Title)+"»</i>")
+		}
+		for _, member := range group.Members {
+			lines = append(lines, htmlEscape(this.memberToDot(member)))
+		}
+		if hidden > 0 && index == len(groups)-1 {
+			lines = append(lines, fmt.Sprintf("... %d more", hidden))
+		}
+
+		result += "<tr><td align=\"left\" balign=\"left\">" + strings.Join(lines, "<br/>") + "</td></tr>"
+	}
+
+	if len(groups) == 0 {
+		result += "<tr><td></td></tr>"
+	}
+
+	return result
+}
+
+func (this *dotRenderer) memberToDot(member *memberMeta) string {
+
+	if !member.Method {
+		return member.Visibility + strings.TrimSpace(member.Name+" "+member.Type)
+	}
+
+	line := member.Visibility + member.Name + "(" + member.Params + ")"
+	if member.Results != "" {
+		line += " " + member.Results
+	}
+	if member.PointerReceiver {
+		line += " «pointer»"
+	}
+
+	return line
+}
+
+/**
+ * 关系的边, 多重性显示在target端, map的key类型显示在source端
+ */
+func (this *dotRenderer) relationToDot(d *DependencyRelation) string {
+
+	style := dotEdgeStyles[d.kind]
+
+	if d.kind == relationEmbeds {
+		style = "style=dashed, dir=both, arrowtail=diamond, arrowhead=none"
+		if d.pointer {
+			style = "style=dashed, dir=both, arrowtail=odiamond, arrowhead=none"
+		}
+	}
+
+	attributes := []string{style}
+
+	label := d.label
+	if d.stereotype != "" {
+		label = strings.TrimSpace(label + " «" + d.stereotype + "»")
+	}
+	if label != "" {
+		attributes = append(attributes, "label="+dotQuote(label))
+	}
+	if d.multiplicity != "" {
+		attributes = append(attributes, "headlabel="+dotQuote(d.multiplicity))
+	}
+	if d.qualifier != "" {
+		attributes = append(attributes, "taillabel="+dotQuote("["+d.qualifier+"]"))
+	}
+	if this.tool.highlightCycle(d.cycle) {
+		attributes = append(attributes, "color=red")
+	}
+
+	return this.nodeId(d.source) + " -> " + this.nodeId(d.target) + " [" + strings.Join(attributes, ", ") + "]"
+}
+
+/**
+ * 实现关系画成虚线和空心三角形, 只有*T实现时加上«pointer»
+ */
+func (this *dotRenderer) implToDot(interfaceMeta1 *interfaceMeta, interfaceImpl1 *interfaceImpl) string {
+	edge := this.nodeId(interfaceImpl1) + " -> " + this.nodeId(interfaceMeta1) + " [style=dashed, arrowhead=empty"
+	if interfaceImpl1.PointerOnly {
+		edge += ", label=\"«pointer»\""
+	}
+	return edge + "]"
+}
+
+var dotPackageColors = map[string]string{
+	PackageStdlib:   "#eeeeee",
+	PackageVendor:   "#fff3cd",
+	PackageExternal: "#dde8f6",
+}
+
+/**
+ * 包的import关系图, 边的weight为import的文件数量, 引用越多的包在布局中越靠近
+ */
+func (this *dotRenderer) renderPackages() string {
+
+	result := "digraph G {\n" +
+		"  rankdir=LR;\n" +
+		"  node [shape=folder, fontname=\"Helvetica\", fontsize=10];\n" +
+		"  edge [fontname=\"Helvetica\", fontsize=9];\n"
+
+	for _, node := range this.tool.shownPackageNodes() {
+		if node.Kind == packageInternal {
+			result += "  " + dotQuote(node.Name) + ";\n"
+			continue
+		}
+		result += "  " + dotQuote(node.Name) + " [label=<" + htmlEscape(node.Name) + "<br/><i>«" + node.Kind + "»</i>>" +
+			", style=filled, fillcolor=" + dotQuote(dotPackageColors[node.Kind]) + "];\n"
+	}
+
+	for _, edge := range this.tool.shownPackageEdges() {
+		color := ""
+		if this.tool.highlightCycle(edge.cycle) {
+			color = ", color=red"
+		}
+		result += fmt.Sprintf("  %s -> %s [label=\"%d\", weight=%d%s];\n", dotQuote(edge.source.Name), dotQuote(edge.target.Name), edge.Weight, edge.Weight, color)
+	}
+
+	return result + "}\n"
+}
@@ -0,0 +1,167 @@
+package codeanalysis
+
+import (
+	"go/token"
+)
+
+// struct的字段
+type fieldMeta struct {
+	// 字段名, 匿名嵌入的字段为嵌入的类型名
+	Name string
+	// 字段类型, 例如 map[string]sub2.Sub2A
+	Type string
+	// 是否为匿名嵌入的字段
+	Embedded bool
+}
+
+// 嵌入类型提升的字段或方法
+type promotedMember struct {
+	Name    string
+	Member  *memberMeta
+	IsField bool
+	// 嵌入的深度, 直接嵌入的类型的成员为1
+	Depth int
+}
+
+/**
+ * 嵌入关系, interface嵌入interface画成泛化 Inner <|-- Outer
+ * struct嵌入类型画成 Outer *.. Inner : <<embeds>>, 以指针方式嵌入时画成 Outer o.. Inner : <<embeds>>
+ */
+func (this *analysisTool) visitEmbed(source typeMeta, embedMeta1 *embedMeta, position token.Position) {
+
+	var target typeMeta
+	if structMeta1 := this.findStruct(embedMeta1.PackagePath, embedMeta1.Name); structMeta1 != nil {
+		target = structMeta1
+	} else if interfaceMeta1 := this.findInterfaceMeta(embedMeta1.PackagePath, embedMeta1.Name); interfaceMeta1 != nil {
+		target = interfaceMeta1
+	}
+
+	if target == nil || target == source {
+		return
+	}
+
+	d := DependencyRelation{
+		source:   source,
+		target:   target,
+		position: position,
+	}
+
+	switch source.(type) {
+	case *interfaceMeta:
+		if _, ok := target.(*interfaceMeta); !ok {
+			return
+		}
+		d.kind = relationExtends
+	case *structMeta:
+		d.kind = relationEmbeds
+		d.stereotype = "embeds"
+		d.pointer = embedMeta1.Pointer
+	}
+
+	this.dependencyRelations = append(this.dependencyRelations, &d)
+}
+
+/**
+ * 嵌入类型提升的字段和方法, 每个嵌入类型一组, 标题为 embedded X
+ * 和Go的选择器规则相同, 同名的成员只提升深度最浅的, 被外层同名字段或方法覆盖的, 以及最浅的深度上有多个同名的不显示
+ */
+func (this *analysisTool) promotedMembers(source typeMeta) []*memberGroup {
+
+	declared := map[string]bool{}
+	embeds := []*embedMeta{}
+
+	switch meta := source.(type) {
+	case *structMeta:
+		for _, field := range meta.Fields {
+			declared[field.Name] = true
+		}
+		for _, method := range meta.Methods {
+			declared[method.Name] = true
+		}
+		embeds = meta.Embeds
+	case *interfaceMeta:
+		for _, method := range meta.Methods {
+			declared[method.Name] = true
+		}
+		embeds = meta.Embeds
+	}
+
+	groups := [][]*promotedMember{}
+	// 每个名字最浅的深度, 以及每个深度上同名成员的数量
+	shallowest := map[string]int{}
+	count := map[string]map[int]int{}
+
+	for _, embed := range embeds {
+		members := this.embeddedMembers(embed, 1, map[string]bool{})
+		groups = append(groups, members)
+
+		for _, member := range members {
+			if depth, ok := shallowest[member.Name]; !ok || member.Depth < depth {
+				shallowest[member.Name] = member.Depth
+			}
+			if count[member.Name] == nil {
+				count[member.Name] = map[int]int{}
+			}
+			count[member.Name][member.Depth]++
+		}
+	}
+
+	result := []*memberGroup{}
+
+	for index, members := range groups {
+
+		group := &memberGroup{Title: "embedded " + embeds[index].Name}
+
+		for _, member := range members {
+			if declared[member.Name] || member.Depth != shallowest[member.Name] || count[member.Name][member.Depth] != 1 {
+				continue
+			}
+			if member.IsField && this.showField(member.Name) || !member.IsField && this.showMethod(member.Name) {
+				group.Members = append(group.Members, member.Member)
+			}
+		}
+
+		result = append(result, group)
+	}
+
+	return result
+}
+
+/**
+ * 嵌入类型的字段和方法, 包含它再嵌入的类型提升的字段和方法, 不在分析结果中的类型返回空
+ * depth为embed中的成员的深度, 再嵌入的类型的成员深度加1
+ */
+func (this *analysisTool) embeddedMembers(embed *embedMeta, depth int, visited map[string]bool) []*promotedMember {
+
+	key := embed.PackagePath + "." + embed.Name
+	if visited[key] {
+		return nil
+	}
+	visited[key] = true
+
+	members := []*promotedMember{}
+	embeds := []*embedMeta{}
+
+	if structMeta1 := this.findStruct(embed.PackagePath, embed.Name); structMeta1 != nil {
+		for _, field := range structMeta1.Fields {
+			if !field.Embedded {
+				members = append(members, &promotedMember{Name: field.Name, Member: field.member(), IsField: true, Depth: depth})
+			}
+		}
+		for _, method := range structMeta1.Methods {
+			members = append(members, &promotedMember{Name: method.Name, Member: method.member(), Depth: depth})
+		}
+		embeds = structMeta1.Embeds
+	} else if interfaceMeta1 := this.findInterfaceMeta(embed.PackagePath, embed.Name); interfaceMeta1 != nil {
+		for _, method := range interfaceMeta1.Methods {
+			members = append(members, &promotedMember{Name: method.Name, Member: method.member(), Depth: depth})
+		}
+		embeds = interfaceMeta1.Embeds
+	}
+
+	for _, inner := range embeds {
+		members = append(members, this.embeddedMembers(inner, depth+1, visited)...)
+	}
+
+	return members
+}
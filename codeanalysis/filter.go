@@ -0,0 +1,257 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/**
+ * 编译类型名的正则表达式, 检查包和文件的匹配规则
+ */
+func (this *analysisTool) compileFilters() error {
+
+	for _, pattern := range append(append(append(append([]string{}, this.config.IncludePackages...), this.config.ExcludePackages...),
+		this.config.IncludeFiles...), this.config.ExcludeFiles...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("匹配规则%s格式错误", pattern)
+		}
+	}
+
+	compile := func(patterns []string) ([]*regexp.Regexp, error) {
+		result := []*regexp.Regexp{}
+		for _, pattern := range patterns {
+			regexp1, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("类型名的正则表达式%s格式错误, %s", pattern, err)
+			}
+			result = append(result, regexp1)
+		}
+		return result, nil
+	}
+
+	var err error
+	if this.includeTypes, err = compile(this.config.IncludeTypes); err != nil {
+		return err
+	}
+	if this.excludeTypes, err = compile(this.config.ExcludeTypes); err != nil {
+		return err
+	}
+
+	if this.config.UseGitignore {
+		this.gitignore = loadGitignore(this.config.CodeDir)
+	}
+
+	return nil
+}
+
+/**
+ * 遍历代码目录中需要解析的go文件, .gitignore中忽略的目录不再进入
+ */
+func (this *analysisTool) walkGoFiles(visit func(file string)) {
+
+	codeDir := filepath.Clean(this.config.CodeDir)
+
+	filepath.Walk(codeDir, func(file string, info os.FileInfo, err error) error {
+
+		if err != nil {
+			return nil
+		}
+
+		if info.IsDir() {
+			if file != codeDir && this.gitignore != nil && this.gitignore.ignored(file, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if this.includeFile(file) {
+			log.Info("解析 " + file)
+			visit(file)
+		}
+
+		return nil
+	})
+}
+
+/**
+ * 没有设置Tests时过滤掉_test.go文件, 过滤掉IgnoreDirs中的目录, 不满足构建约束的文件, 不匹配IncludeFiles, 匹配ExcludeFiles和.gitignore中的文件, Generated为skip时过滤掉生成的代码
+ */
+func (this *analysisTool) includeFile(file string) bool {
+
+	if !strings.HasSuffix(file, ".go") || (isTestFile(file) && !this.config.Tests) {
+		return false
+	}
+
+	if inSomeDir(file, this.config.IgnoreDirs) {
+		return false
+	}
+
+	if !this.matchBuildContext(file) {
+		return false
+	}
+
+	relativePath := strings.TrimPrefix(file, filepath.Clean(this.config.CodeDir)+"/")
+
+	if len(this.config.IncludeFiles) > 0 && !matchFilePatterns(this.config.IncludeFiles, relativePath) {
+		return false
+	}
+
+	if matchFilePatterns(this.config.ExcludeFiles, relativePath) {
+		return false
+	}
+
+	if this.gitignore != nil && this.gitignore.ignored(file, false) {
+		return false
+	}
+
+	if this.config.Generated == GeneratedShow {
+		return true
+	}
+
+	// 其他模式需要记录生成的文件, 用于显示<<generated>>和合并
+	return !this.isGeneratedFile(file) || this.config.Generated != GeneratedSkip
+}
+
+/**
+ * 路径是否为某个目录或在某个目录中, 按照路径的每一段匹配, /a/foo 不包含 /a/foobar
+ */
+func inSomeDir(file string, dirs []string) bool {
+	for _, dir := range dirs {
+		dir = filepath.Clean(dir)
+		if file == dir || strings.HasPrefix(file, strings.TrimSuffix(dir, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 文件名的匹配规则, 没有/时匹配文件名, 例如 *_gen.go, 有/时匹配相对于代码目录的路径, 例如 internal/**\/mock_*.go
+ */
+func matchFilePatterns(patterns []string, relativePath string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(pattern, "/") {
+			if matchPackagePattern(pattern, relativePath) {
+				return true
+			}
+		} else if matched, _ := path.Match(pattern, path.Base(relativePath)); matched {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 包是否匹配IncludePackages, 并且不匹配ExcludePackages
+ */
+func (this *analysisTool) packageIncluded(packagePath string) bool {
+
+	if len(this.config.IncludePackages) > 0 && !this.matchPackage(this.config.IncludePackages, packagePath) {
+		return false
+	}
+
+	return !this.matchPackage(this.config.ExcludePackages, packagePath)
+}
+
+/**
+ * 类型所在的包和类型名是否匹配包含和排除规则
+ */
+func (this *analysisTool) typeIncluded(typeMeta1 typeMeta) bool {
+
+	if !this.packageIncluded(typeMetaPackagePath(typeMeta1)) {
+		return false
+	}
+
+	name := typeMetaName(typeMeta1)
+
+	if len(this.includeTypes) > 0 && !matchRegexps(this.includeTypes, name) {
+		return false
+	}
+
+	return !matchRegexps(this.excludeTypes, name)
+}
+
+func matchRegexps(regexps []*regexp.Regexp, value string) bool {
+	for _, regexp1 := range regexps {
+		if regexp1.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * Detail是否显示该类型, public-api时只显示导出的类型
+ */
+func (this *analysisTool) detailShowsType(typeMeta1 typeMeta) bool {
+	if this.config.Detail == DetailPublicAPI {
+		return ast.IsExported(typeMetaName(typeMeta1))
+	}
+	return true
+}
+
+/**
+ * 被图中的类型依赖或者实现的排除类型, 做为灰色的边界类型显示
+ */
+func (this *analysisTool) computeExcludedStubs() {
+
+	stubs := map[typeMeta]bool{}
+
+	addStub := func(source typeMeta, target typeMeta) {
+		if this.showType(source) && !this.isStub(source) && !this.typeIncluded(target) && this.detailShowsType(target) {
+			stubs[target] = true
+		}
+	}
+
+	for _, d := range this.dependencyRelations {
+		addStub(d.source, d.target)
+	}
+
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		for _, interfaceImpl1 := range this.findInterfaceImpls(interfaceMeta1) {
+			addStub(interfaceImpl1.structMeta, interfaceMeta1)
+		}
+	}
+
+	this.excludedStubs = stubs
+}
+
+/**
+ * 是否为被排除的边界类型
+ */
+func (this *analysisTool) isExcluded(typeMeta1 typeMeta) bool {
+	if interfaceImpl1, ok := typeMeta1.(*interfaceImpl); ok {
+		typeMeta1 = interfaceImpl1.structMeta
+	}
+	return this.excludedStubs[typeMeta1]
+}
+
+/**
+ * 包的import关系图中显示的包, 按照IncludePackages和ExcludePackages过滤
+ */
+func (this *analysisTool) shownPackageNodes() []*packageNode {
+	result := []*packageNode{}
+	for _, node := range this.packageNodes {
+		if this.packageIncluded(node.Name) {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func (this *analysisTool) shownPackageEdges() []*packageEdge {
+	result := []*packageEdge{}
+	for _, edge := range this.packageEdges {
+		if this.packageIncluded(edge.source.Name) && this.packageIncluded(edge.target.Name) {
+			result = append(result, edge)
+		}
+	}
+	return result
+}
@@ -0,0 +1,178 @@
+package codeanalysis
+
+import (
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Focus时沿着关系的方向
+const (
+	// 从类型出发, 沿着它依赖, 嵌入和实现的类型
+	FocusOut = "out"
+	// 依赖, 嵌入和实现了该类型的类型
+	FocusIn   = "in"
+	FocusBoth = "both"
+)
+
+var focusDirections = []string{FocusOut, FocusIn, FocusBoth}
+
+/**
+ * 从Focus的类型出发, 计算FocusDepth步以内可以到达的类型, 第FocusDepth+1步到达的类型做为折叠的边界类型
+ */
+func (this *analysisTool) computeFocus() {
+
+	root := this.findFocusType(this.config.Focus)
+	if root == nil {
+		return
+	}
+
+	this.focusTypes = map[typeMeta]int{}
+
+	// 依赖关系和实现关系, 从source指向target
+	outgoing := map[typeMeta][]typeMeta{}
+	incoming := map[typeMeta][]typeMeta{}
+	addEdge := func(source typeMeta, target typeMeta) {
+		outgoing[source] = append(outgoing[source], target)
+		incoming[target] = append(incoming[target], source)
+	}
+
+	for _, d := range this.dependencyRelations {
+		addEdge(d.source, d.target)
+	}
+
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		for _, interfaceImpl1 := range this.findInterfaceImpls(interfaceMeta1) {
+			addEdge(interfaceImpl1.structMeta, interfaceMeta1)
+		}
+	}
+
+	this.focusTypes[root] = 0
+	current := []typeMeta{root}
+
+	for depth := 1; depth <= this.config.FocusDepth+1 && len(current) > 0; depth++ {
+
+		next := []typeMeta{}
+
+		for _, node := range current {
+
+			neighbours := []typeMeta{}
+			if this.config.FocusDirection != FocusIn {
+				neighbours = append(neighbours, outgoing[node]...)
+			}
+			if this.config.FocusDirection != FocusOut {
+				neighbours = append(neighbours, incoming[node]...)
+			}
+
+			for _, neighbour := range neighbours {
+				if _, ok := this.focusTypes[neighbour]; !ok {
+					this.focusTypes[neighbour] = depth
+					next = append(next, neighbour)
+				}
+			}
+		}
+
+		current = next
+	}
+
+	log.Infof("从%s出发%d步以内的类型有%d个", this.config.Focus, this.config.FocusDepth, len(this.focusTypes))
+}
+
+/**
+ * 按照 包路径.类型名 查找struct或interface, 包路径可以是相对于代码目录的路径, 只有类型名时在所有包中查找
+ * 找不到或者不唯一时分析失败
+ */
+func (this *analysisTool) findFocusType(focus string) typeMeta {
+
+	packagePath := ""
+	name := focus
+	if index := strings.LastIndex(focus, "."); index >= 0 {
+		packagePath = focus[:index]
+		name = focus[index+1:]
+	}
+
+	rootPackagePath := this.dirToPackagePath(path.Clean(this.config.CodeDir))
+
+	matched := []typeMeta{}
+	match := func(node typeMeta) {
+		nodePackagePath := typeMetaPackagePath(node)
+		if typeMetaName(node) == name && (packagePath == "" || nodePackagePath == packagePath ||
+			nodePackagePath == rootPackagePath+"/"+packagePath) {
+			matched = append(matched, node)
+		}
+	}
+
+	for _, structMeta1 := range this.structMetas {
+		match(structMeta1)
+	}
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		match(interfaceMeta1)
+	}
+
+	if len(matched) == 0 {
+		this.fail("找不到类型%s, 请使用 包路径.类型名 的格式\n", focus)
+		return nil
+	}
+
+	if len(matched) > 1 {
+		names := []string{}
+		for _, node := range matched {
+			names = append(names, typeMetaPackagePath(node)+"."+typeMetaName(node))
+		}
+		this.fail("类型%s不唯一, 请使用包路径区分: %s\n", focus, strings.Join(names, ", "))
+		return nil
+	}
+
+	return matched[0]
+}
+
+/**
+ * Focus时类型是否在图中, 类型别名跟随指向的类型, 没有设置Focus时始终为true
+ */
+func (this *analysisTool) inFocus(typeMeta1 typeMeta) bool {
+
+	if this.focusTypes == nil {
+		return true
+	}
+
+	switch meta := typeMeta1.(type) {
+	case *interfaceImpl:
+		typeMeta1 = meta.structMeta
+	case *typeAliasMeta:
+		target := this.typeAliasTarget(meta)
+		if target == nil || this.isStub(target) {
+			return false
+		}
+		typeMeta1 = target
+	}
+
+	_, ok := this.focusTypes[typeMeta1]
+
+	return ok
+}
+
+/**
+ * 超出FocusDepth的边界类型和被排除的边界类型, 只显示类型名, 不显示成员
+ */
+func (this *analysisTool) isStub(typeMeta1 typeMeta) bool {
+
+	if interfaceImpl1, ok := typeMeta1.(*interfaceImpl); ok {
+		typeMeta1 = interfaceImpl1.structMeta
+	}
+
+	if this.excludedStubs[typeMeta1] {
+		return true
+	}
+
+	depth, ok := this.focusTypes[typeMeta1]
+
+	return ok && depth > this.config.FocusDepth
+}
+
+/**
+ * 两个类型之间的边是否显示, 两端都要显示, 边界类型之间的边不显示
+ */
+func (this *analysisTool) showRelation(source typeMeta, target typeMeta) bool {
+	return this.showType(source) && this.showType(target) && !(this.isStub(source) && this.isStub(target))
+}
@@ -0,0 +1,148 @@
+package codeanalysis
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+)
+
+// 生成的代码的处理方式
+const (
+	// 和手写的代码一样显示
+	GeneratedShow = "show"
+	// 不解析生成的代码
+	GeneratedSkip = "skip"
+	// 生成的类型加上<<generated>>, 和同一个包中手写的类型分开显示
+	GeneratedSeparate = "separate"
+	// 每个包中生成的类型合并为一个节点
+	GeneratedCollapse = "collapse"
+)
+
+var generatedModes = []string{GeneratedShow, GeneratedSkip, GeneratedSeparate, GeneratedCollapse}
+
+// 合并后的节点的类型名
+const generatedNodeName = "generated"
+
+/**
+ * 文件是否有 // Code generated ... DO NOT EDIT. 的注释, 只解析package之前的部分
+ */
+func (this *analysisTool) isGeneratedFile(file string) bool {
+
+	if generated, ok := this.generatedFiles[file]; ok {
+		return generated
+	}
+
+	generated := false
+	if astFile, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly|parser.ParseComments); err == nil {
+		generated = ast.IsGenerated(astFile)
+	}
+	this.generatedFiles[file] = generated
+
+	return generated
+}
+
+/**
+ * 类型是否在生成的代码中, 合并后的节点也是生成的
+ */
+func (this *analysisTool) isGenerated(typeMeta1 typeMeta) bool {
+	switch meta := typeMeta1.(type) {
+	case *structMeta:
+		return len(meta.CollapsedTypes) > 0 || this.generatedFiles[meta.FilePath]
+	case *interfaceMeta:
+		return this.generatedFiles[meta.FilePath]
+	case *typeAliasMeta:
+		return this.generatedFiles[meta.FilePath]
+	case *interfaceImpl:
+		return this.isGenerated(meta.structMeta)
+	}
+	return false
+}
+
+/**
+ * 生成的类型是否使用<<generated>>的样式单独分组
+ */
+func (this *analysisTool) generatedStyle(typeMeta1 typeMeta) bool {
+	return (this.config.Generated == GeneratedSeparate || this.config.Generated == GeneratedCollapse) && this.isGenerated(typeMeta1)
+}
+
+/**
+ * 把每个包中生成的struct和interface合并为一个节点, 成员为合并的类型名, 指向这些类型的关系改为指向合并后的节点
+ */
+func (this *analysisTool) collapseGenerated() {
+
+	collapsed := map[typeMeta]*structMeta{}
+	nodes := map[string]*structMeta{}
+	packagePaths := []string{}
+
+	collapse := func(typeMeta1 typeMeta, packagePath string, name string) {
+		node, ok := nodes[packagePath]
+		if !ok {
+			node = &structMeta{
+				baseInfo: baseInfo{PackagePath: packagePath},
+				Name:     generatedNodeName,
+			}
+			nodes[packagePath] = node
+			packagePaths = append(packagePaths, packagePath)
+		}
+		node.CollapsedTypes = append(node.CollapsedTypes, name)
+		collapsed[typeMeta1] = node
+	}
+
+	structMetas := []*structMeta{}
+	for _, structMeta1 := range this.structMetas {
+		if this.isGenerated(structMeta1) {
+			collapse(structMeta1, structMeta1.PackagePath, structMeta1.Name)
+		} else {
+			structMetas = append(structMetas, structMeta1)
+		}
+	}
+
+	interfaceMetas := []*interfaceMeta{}
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		if this.isGenerated(interfaceMeta1) {
+			collapse(interfaceMeta1, interfaceMeta1.PackagePath, interfaceMeta1.Name)
+		} else {
+			interfaceMetas = append(interfaceMetas, interfaceMeta1)
+		}
+	}
+
+	if len(collapsed) == 0 {
+		return
+	}
+
+	for _, packagePath := range packagePaths {
+		structMetas = append(structMetas, nodes[packagePath])
+	}
+
+	this.structMetas = structMetas
+	this.interfaceMetas = interfaceMetas
+
+	// 合并后同一个节点内部的关系不再显示, 相同的关系只保留一个
+	relations := []*DependencyRelation{}
+	for _, d := range this.dependencyRelations {
+
+		source, target := collapsed[d.source], collapsed[d.target]
+		if source != nil && source == target {
+			continue
+		}
+		if source != nil {
+			d.source = source
+		}
+		if target != nil {
+			d.target = target
+		}
+
+		exist := false
+		for _, relation := range relations {
+			if relation.source == d.source && relation.target == d.target && relation.kind == d.kind && relation.label == d.label {
+				exist = true
+				break
+			}
+		}
+		if !exist {
+			relations = append(relations, d)
+		}
+	}
+
+	this.dependencyRelations = relations
+}
@@ -0,0 +1,175 @@
+package codeanalysis
+
+import (
+	"go/ast"
+	"strings"
+)
+
+// 泛型类型的类型参数, 例如 [K comparable, V any] 中的 K comparable
+type typeParamMeta struct {
+	Name string
+	// 类型约束, 例如 any, comparable, ~int | ~string
+	Constraint string
+}
+
+func (this *analysisTool) createTypeParamMetas(typeParams *ast.FieldList) []*typeParamMeta {
+
+	metas := []*typeParamMeta{}
+
+	if typeParams == nil {
+		return metas
+	}
+
+	for _, field := range typeParams.List {
+		constraint := this.typeToString(field.Type, false)
+		for _, name := range field.Names {
+			metas = append(metas, &typeParamMeta{
+				Name:       name.Name,
+				Constraint: constraint,
+			})
+		}
+	}
+
+	return metas
+}
+
+/**
+ * 类型参数转换为UML的泛型声明, 例如 <K comparable, V>, 约束为any时省略
+ */
+func typeParamsToUML(typeParams []*typeParamMeta) string {
+
+	if len(typeParams) == 0 {
+		return ""
+	}
+
+	params := []string{}
+	for _, typeParam := range typeParams {
+		if typeParam.Constraint == "" || typeParam.Constraint == "any" || typeParam.Constraint == "interface {}" {
+			params = append(params, typeParam.Name)
+		} else {
+			params = append(params, typeParam.Name+" "+typeParam.Constraint)
+		}
+	}
+
+	return "<" + strings.Join(params, ", ") + ">"
+}
+
+/**
+ * 去掉泛型实例化的类型实参, 例如 List[T] 返回 List
+ */
+func genericBaseType(t ast.Expr) ast.Expr {
+
+	switch expr := t.(type) {
+	case *ast.IndexExpr:
+		return expr.X
+	case *ast.IndexListExpr:
+		return expr.X
+	}
+
+	return t
+}
+
+/**
+ * 泛型实例化的类型实参, 例如 List[T] 返回 [T], Map[K, V] 返回 [K, V]
+ */
+func genericTypeArgs(t ast.Expr) []ast.Expr {
+
+	switch expr := t.(type) {
+	case *ast.IndexExpr:
+		return []ast.Expr{expr.Index}
+	case *ast.IndexListExpr:
+		return expr.Indices
+	}
+
+	return nil
+}
+
+/**
+ * 收集类型表达式中所有泛型实例化的类型实参, 例如 []Box[User] 返回 [User]
+ */
+func collectTypeArgs(t ast.Expr) []ast.Expr {
+
+	result := []ast.Expr{}
+
+	switch expr := t.(type) {
+	case *ast.StarExpr:
+		result = append(result, collectTypeArgs(expr.X)...)
+	case *ast.ArrayType:
+		result = append(result, collectTypeArgs(expr.Elt)...)
+	case *ast.MapType:
+		result = append(result, collectTypeArgs(expr.Key)...)
+		result = append(result, collectTypeArgs(expr.Value)...)
+	case *ast.ChanType:
+		result = append(result, collectTypeArgs(expr.Value)...)
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		for _, arg := range genericTypeArgs(expr) {
+			result = append(result, arg)
+			result = append(result, collectTypeArgs(arg)...)
+		}
+	}
+
+	return result
+}
+
+/**
+ * 泛型接收者声明的类型参数名, 例如 func (this *List[T]) 返回 [T]
+ */
+func receiverTypeParamNames(recv ast.Expr) []string {
+
+	starExpr, ok := recv.(*ast.StarExpr)
+	if ok {
+		recv = starExpr.X
+	}
+
+	names := []string{}
+	for _, arg := range genericTypeArgs(recv) {
+		ident, ok := arg.(*ast.Ident)
+		if ok {
+			names = append(names, ident.Name)
+		}
+	}
+
+	return names
+}
+
+func typeParamNames(typeParams []*typeParamMeta) []string {
+	names := []string{}
+	for _, typeParam := range typeParams {
+		names = append(names, typeParam.Name)
+	}
+	return names
+}
+
+/**
+ * 当前解析的泛型类型或方法中, name是否为类型参数
+ */
+func (this *analysisTool) isTypeParam(name string) bool {
+	return sliceContains(this.currentTypeParams, name)
+}
+
+/**
+ * interface中的类型约束元素, 例如 ~int | ~string, 嵌入的interface返回false
+ */
+func (this *analysisTool) isTypeConstraintElement(t ast.Expr) bool {
+
+	switch expr := t.(type) {
+	case *ast.UnaryExpr, *ast.BinaryExpr:
+		return true
+	case *ast.Ident:
+		if this.isGoBaseType(expr.Name) && expr.Name != "error" {
+			return true
+		}
+	}
+
+	if packagePath, name, ok := this.resolveTypeExpr(t); ok {
+		if packagePath == "" {
+			return name != "error"
+		}
+		return !this.isInterfaceType(packagePath, name)
+	}
+
+	embedMeta1 := this.createEmbedMeta(t)
+
+	return this.findStruct(embedMeta1.PackagePath, embedMeta1.Name) != nil ||
+		this.findTypeAlias(embedMeta1.PackagePath, embedMeta1.Name) != nil
+}
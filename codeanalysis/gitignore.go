@@ -0,0 +1,137 @@
+package codeanalysis
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// .gitignore中的一条规则
+type gitignoreRule struct {
+	// .gitignore所在的目录
+	dir     string
+	pattern string
+	// 以!开头, 重新包含前面排除的文件
+	negate bool
+	// 以/结尾, 只匹配目录
+	dirOnly bool
+	// 开头或中间有/, 相对于.gitignore所在的目录匹配, 否则匹配任意一层的文件名
+	anchored bool
+}
+
+// 代码目录和上级目录中的.gitignore, 后面的规则优先
+type gitignore struct {
+	rules []*gitignoreRule
+}
+
+/**
+ * 读取代码目录到git仓库根目录之间的.gitignore, 以及代码目录中所有子目录的.gitignore
+ */
+func loadGitignore(codeDir string) *gitignore {
+
+	this := &gitignore{}
+
+	codeDir = filepath.Clean(codeDir)
+
+	// 代码目录在git仓库的子目录中时, 上级目录中的.gitignore也生效
+	if !PathExists(path.Join(codeDir, ".git")) {
+		parents := []string{}
+		for dir := path.Dir(codeDir); dir != path.Dir(dir); dir = path.Dir(dir) {
+			parents = append([]string{dir}, parents...)
+			if PathExists(path.Join(dir, ".git")) {
+				for _, parent := range parents {
+					this.load(parent)
+				}
+				break
+			}
+		}
+	}
+
+	filepath.Walk(codeDir, func(file string, info os.FileInfo, err error) error {
+		if err == nil && info.IsDir() {
+			this.load(file)
+		}
+		return nil
+	})
+
+	return this
+}
+
+func (this *gitignore) load(dir string) {
+
+	file, err := os.Open(path.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := &gitignoreRule{dir: dir}
+
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+
+		rule.pattern = line
+		this.rules = append(this.rules, rule)
+	}
+}
+
+/**
+ * 文件或目录是否被忽略, 所在的目录被忽略时文件也被忽略
+ */
+func (this *gitignore) ignored(file string, isDir bool) bool {
+
+	file = filepath.Clean(file)
+
+	for dir := path.Dir(file); dir != path.Dir(dir); dir = path.Dir(dir) {
+		if this.match(dir, true) {
+			return true
+		}
+	}
+
+	return this.match(file, isDir)
+}
+
+func (this *gitignore) match(file string, isDir bool) bool {
+
+	ignored := false
+
+	for _, rule := range this.rules {
+
+		if !strings.HasPrefix(file, rule.dir+"/") || rule.dirOnly && !isDir {
+			continue
+		}
+
+		matched := false
+		if rule.anchored {
+			matched = matchPackagePattern(rule.pattern, strings.TrimPrefix(file, rule.dir+"/"))
+		} else {
+			matched, _ = path.Match(rule.pattern, path.Base(file))
+		}
+
+		if matched {
+			ignored = !rule.negate
+		}
+	}
+
+	return ignored
+}
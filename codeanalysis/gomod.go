@@ -0,0 +1,293 @@
+package codeanalysis
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// go.mod文件解析出的模块信息
+type moduleMeta struct {
+	// go.mod所在目录, 例如 /appdev/list-interface
+	Dir      string
+	// 模块路径, 例如 github.com/maobuji/list-interface
+	Path     string
+	// require的模块路径与版本的映射关系
+	Requires map[string]string
+	// replace指令
+	Replaces []*moduleReplace
+}
+
+// go.mod中的一条replace指令, 例如 github.com/a/b v1.0.0 => ../b
+type moduleReplace struct {
+	OldPath    string
+	OldVersion string
+	NewPath    string
+	NewVersion string
+}
+
+// 替换目标是否为本地目录
+func (this *moduleReplace) isLocal() bool {
+	return strings.HasPrefix(this.NewPath, "./") || strings.HasPrefix(this.NewPath, "../") ||
+		filepath.IsAbs(this.NewPath)
+}
+
+/**
+ * 从dir开始向上查找最近的go.mod文件, 找不到返回空字符串
+ */
+func findGoModFile(dir string) string {
+	dir = filepath.Clean(dir)
+	for {
+		gomod := filepath.Join(dir, "go.mod")
+		if PathExists(gomod) {
+			return gomod
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+/**
+ * 解析go.mod文件, 只关心module, require, replace三种指令
+ */
+func parseGoModFile(gomodPath string) (*moduleMeta, error) {
+
+	file, err := os.Open(gomodPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	module := &moduleMeta{
+		Dir:      filepath.Dir(gomodPath),
+		Requires: map[string]string{},
+		Replaces: []*moduleReplace{},
+	}
+
+	block := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "//"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if block != "" {
+			if line == ")" {
+				block = ""
+			} else {
+				module.parseDirective(block, line)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+
+		module.parseDirective(fields[0], strings.TrimSpace(strings.TrimPrefix(line, fields[0])))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return module, nil
+}
+
+func (this *moduleMeta) parseDirective(verb string, args string) {
+
+	fields := strings.Fields(args)
+	for index, field := range fields {
+		fields[index] = strings.Trim(field, "\"`")
+	}
+
+	switch verb {
+	case "module":
+		if len(fields) > 0 {
+			this.Path = fields[0]
+		}
+	case "require":
+		if len(fields) >= 2 {
+			this.Requires[fields[0]] = fields[1]
+		}
+	case "replace":
+		arrow := -1
+		for index, field := range fields {
+			if field == "=>" {
+				arrow = index
+			}
+		}
+		if arrow < 1 || arrow == len(fields)-1 {
+			log.Warnf("无法解析replace指令%s, go.mod目录%s", args, this.Dir)
+			return
+		}
+
+		replace := &moduleReplace{
+			OldPath: fields[0],
+			NewPath: fields[arrow+1],
+		}
+		if arrow == 2 {
+			replace.OldVersion = fields[1]
+		}
+		if len(fields) > arrow+2 {
+			replace.NewVersion = fields[arrow+2]
+		}
+		this.Replaces = append(this.Replaces, replace)
+	}
+}
+
+/**
+ * 模块缓存中的路径转义规则, 大写字母转换为!加小写字母, 例如 github.com/Sirupsen/logrus 对应 github.com/!sirupsen/logrus
+ */
+func escapeModulePath(modulePath string) string {
+	result := ""
+	for _, r := range modulePath {
+		if unicode.IsUpper(r) {
+			result += "!" + string(unicode.ToLower(r))
+		} else {
+			result += string(r)
+		}
+	}
+	return result
+}
+
+/**
+ * 判断packagePath是否属于modulePath模块, 返回包在模块内的相对路径
+ */
+func trimModulePath(packagePath string, modulePath string) (string, bool) {
+	if packagePath == modulePath {
+		return "", true
+	}
+	if strings.HasPrefix(packagePath, modulePath+"/") {
+		return strings.TrimPrefix(packagePath, modulePath+"/"), true
+	}
+	return "", false
+}
+
+/**
+ * 计算模块缓存目录, 优先级为 配置 > GOMODCACHE环境变量 > GOPATH/pkg/mod
+ */
+func defaultModCacheDir(gopathDir string) string {
+
+	if modcache := os.Getenv("GOMODCACHE"); modcache != "" {
+		return modcache
+	}
+
+	if gopathDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		gopathDir = filepath.Join(home, "go")
+	}
+
+	return filepath.Join(gopathDir, "pkg", "mod")
+}
+
+/**
+ * 在模块缓存中查找 modulePath@version 对应的目录
+ */
+func (this *analysisTool) moduleCachePackageDir(modulePath string, version string, subPath string) string {
+	if this.config.ModCacheDir == "" || version == "" {
+		return ""
+	}
+	return path.Join(this.config.ModCacheDir, escapeModulePath(modulePath)+"@"+version, subPath)
+}
+
+/**
+ * 查找目录所属的模块, 找不到返回nil
+ */
+func (this *analysisTool) findModule(dir string) *moduleMeta {
+
+	if module, ok := this.moduleCache[dir]; ok {
+		return module
+	}
+
+	var module *moduleMeta
+
+	gomod := findGoModFile(dir)
+	if gomod != "" {
+		if cached, ok := this.moduleCache[filepath.Dir(gomod)]; ok {
+			module = cached
+		} else {
+			parsed, err := parseGoModFile(gomod)
+			if err != nil {
+				log.Errorf("解析文件%s失败, %s", gomod, err)
+			} else if parsed.Path == "" {
+				log.Errorf("文件%s中没有module声明", gomod)
+			} else {
+				module = parsed
+			}
+			this.moduleCache[filepath.Dir(gomod)] = module
+		}
+	}
+
+	this.moduleCache[dir] = module
+
+	return module
+}
+
+/**
+ * 根据go.mod中的module, replace, require, 查找包路径对应的源码目录
+ */
+func (this *analysisTool) findPackageDirInModule(module *moduleMeta, packagePath string) string {
+
+	if subPath, ok := trimModulePath(packagePath, module.Path); ok {
+		return path.Join(module.Dir, subPath)
+	}
+
+	// replace优先于require, 多个匹配时使用最长的模块路径
+	var matchedReplace *moduleReplace
+	for _, replace := range module.Replaces {
+		if _, ok := trimModulePath(packagePath, replace.OldPath); ok {
+			if replace.OldVersion != "" && replace.OldVersion != module.Requires[replace.OldPath] {
+				continue
+			}
+			if matchedReplace == nil || len(replace.OldPath) > len(matchedReplace.OldPath) {
+				matchedReplace = replace
+			}
+		}
+	}
+
+	if matchedReplace != nil {
+		subPath, _ := trimModulePath(packagePath, matchedReplace.OldPath)
+		if matchedReplace.isLocal() {
+			newPath := matchedReplace.NewPath
+			if !filepath.IsAbs(newPath) {
+				newPath = path.Join(module.Dir, newPath)
+			}
+			return path.Join(newPath, subPath)
+		}
+		return this.moduleCachePackageDir(matchedReplace.NewPath, matchedReplace.NewVersion, subPath)
+	}
+
+	matchedModulePath := ""
+	for modulePath := range module.Requires {
+		if _, ok := trimModulePath(packagePath, modulePath); ok && len(modulePath) > len(matchedModulePath) {
+			matchedModulePath = modulePath
+		}
+	}
+
+	if matchedModulePath != "" {
+		subPath, _ := trimModulePath(packagePath, matchedModulePath)
+		return this.moduleCachePackageDir(matchedModulePath, module.Requires[matchedModulePath], subPath)
+	}
+
+	return ""
+}
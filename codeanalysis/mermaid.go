@@ -0,0 +1,313 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mermaid格式的类图, 可以直接在Markdown中显示
+type mermaidRenderer struct {
+	tool *analysisTool
+}
+
+var mermaidArrows = map[string]string{
+	relationComposition: "*--",
+	relationAggregation: "o--",
+	relationAssociation: "-->",
+	relationDependency:  "..>",
+}
+
+func (this *mermaidRenderer) render() string {
+
+	if this.tool.config.View == ViewPackages {
+		return this.renderPackages()
+	}
+
+	nodes := []typeMeta{}
+
+	for _, structMeta1 := range this.tool.structMetas {
+		if this.tool.showType(structMeta1) {
+			nodes = append(nodes, structMeta1)
+		}
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if this.tool.showType(interfaceMeta1) {
+			nodes = append(nodes, interfaceMeta1)
+		}
+	}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		nodes = append(nodes, typeAliasMeta1)
+	}
+
+	result := "classDiagram\n"
+
+	// 同一个包中的类型放在一个namespace中, 生成的类型和测试中的类型放在单独的namespace中
+	for _, group := range this.tool.groupNodes(nodes) {
+		namespace := safeId(group.PackagePath)
+		if group.Layer != "" {
+			namespace += "__" + group.Layer
+		}
+		result += "namespace " + namespace + " {\n"
+		for _, node := range group.Nodes {
+			result += this.nodeToMermaid(node)
+		}
+		result += "}\n"
+	}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		if target := this.tool.typeAliasTarget(typeAliasMeta1); target != nil {
+			result += this.nodeId(typeAliasMeta1) + " ..> " + this.nodeId(target) + " : alias\n"
+		}
+	}
+
+	for _, d := range this.tool.shownRelations() {
+		result += this.relationToMermaid(d) + "\n"
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if !this.tool.showType(interfaceMeta1) {
+			continue
+		}
+		for _, interfaceImpl1 := range this.tool.findInterfaceImpls(interfaceMeta1) {
+			if this.tool.showRelation(interfaceImpl1, interfaceMeta1) {
+				result += this.implToMermaid(interfaceMeta1, interfaceImpl1) + "\n"
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if this.tool.isExcluded(node) {
+			result += "style " + this.nodeId(node) + " fill:#eeeeee,stroke:#999999,color:#999999\n"
+		} else if layer := this.tool.layerOf(node); layer != "" {
+			result += "style " + this.nodeId(node) + " fill:" + layerColors[layer][0] + ",stroke:" + layerColors[layer][1] + "\n"
+		}
+	}
+
+	// 类图中不能设置边的颜色, 循环依赖中的类型使用红色边框
+	cycleNodes := []string{}
+	for _, d := range this.tool.shownRelations() {
+		if !this.tool.highlightCycle(d.cycle) {
+			continue
+		}
+		for _, id := range []string{this.nodeId(d.source), this.nodeId(d.target)} {
+			if !sliceContains(cycleNodes, id) {
+				cycleNodes = append(cycleNodes, id)
+				result += "style " + id + " stroke:#ff0000,stroke-width:2px\n"
+			}
+		}
+	}
+
+	return result
+}
+
+/**
+ * 节点的标识符包含包路径, 不同包中的同名类型不会冲突
+ */
+func (this *mermaidRenderer) nodeId(node typeMeta) string {
+	return safeId(typeMetaPackagePath(node) + "." + typeMetaName(node))
+}
+
+/**
+ * 显示的文字中的尖括号使用Mermaid的实体编码
+ */
+func mermaidEscape(text string) string {
+	return strings.NewReplacer("<", "#lt;", ">", "#gt;", "\"", "#quot;").Replace(text)
+}
+
+/**
+ * 节点, 例如 class github_com_a_Box["Box<T>"], 类型的种类使用注解, 例如 <<interface>>, <<enumeration>>
+ */
+func (this *mermaidRenderer) nodeToMermaid(node typeMeta) string {
+
+	label := typeMetaName(node)
+	lines := []string{}
+
+	switch meta := node.(type) {
+	case *structMeta:
+		label += typeParamsToUML(meta.TypeParams)
+		switch {
+		case len(meta.EnumValues) > 0:
+			lines = append(lines, "<<enumeration>>")
+		case meta.UnderlyingType != "":
+			lines = append(lines, "<<"+meta.UnderlyingType+">>")
+		}
+		lines = append(lines, this.membersToMermaid(node)...)
+	case *interfaceMeta:
+		label += typeParamsToUML(meta.TypeParams)
+		if len(meta.TypeUnion) > 0 {
+			lines = append(lines, "<<constraint>>")
+		} else {
+			lines = append(lines, "<<interface>>")
+		}
+		lines = append(lines, this.membersToMermaid(node)...)
+	case *typeAliasMeta:
+		if this.tool.typeAliasTarget(meta) == nil {
+			lines = append(lines, "<<alias "+meta.targetTypeName+">>")
+		} else {
+			lines = append(lines, "<<alias>>")
+		}
+	}
+
+	// Mermaid的类只能有一个注解
+	if layer := this.tool.layerOf(node); layer != "" {
+		lines = append([]string{"<<" + layer + ">>"}, removeAnnotation(lines)...)
+	}
+
+	if this.tool.isStub(node) {
+		lines = []string{"<<stub>>"}
+	}
+
+	// 类只能有一个注解, 构建约束显示在类名后面
+	if platform := this.tool.platformOf(node); platform != "" {
+		label += " «" + platform + "»"
+	}
+
+	result := "  class " + this.nodeId(node) + "[\"" + mermaidEscape(label) + "\"]"
+
+	if len(lines) == 0 {
+		return result + "\n"
+	}
+
+	result += " {\n"
+	for _, line := range lines {
+		result += "    " + line + "\n"
+	}
+	result += "  }\n"
+
+	return result
+}
+
+func removeAnnotation(lines []string) []string {
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "<<") {
+		return lines[1:]
+	}
+	return lines
+}
+
+/**
+ * 节点的成员, 嵌入类型提升的成员前面加上 «embedded X»
+ */
+func (this *mermaidRenderer) membersToMermaid(node typeMeta) []string {
+
+	groups, hidden := this.tool.classMembers(node)
+
+	lines := []string{}
+
+	for _, group := range groups {
+		if group.Title != "" {
+			lines = append(lines, "«"+group.Title+"»")
+		}
+		for _, member := range group.Members {
+			lines = append(lines, this.memberToMermaid(member))
+		}
+	}
+
+	if hidden > 0 {
+		lines = append(lines, fmt.Sprintf("... %d more", hidden))
+	}
+
+	return lines
+}
+
+func (this *mermaidRenderer) memberToMermaid(member *memberMeta) string {
+
+	if !member.Method {
+		return member.Visibility + strings.TrimSpace(member.Name+" "+member.Type)
+	}
+
+	line := member.Visibility + member.Name + "(" + member.Params + ")"
+	if member.Results != "" {
+		line += " " + member.Results
+	}
+	if member.PointerReceiver {
+		line += " «pointer»"
+	}
+
+	return line
+}
+
+/**
+ * 关系, 多重性写在目标端, 例如 A *-- "*" B : items, map的key类型写在标签中
+ */
+func (this *mermaidRenderer) relationToMermaid(d *DependencyRelation) string {
+
+	source := this.nodeId(d.source)
+	target := this.nodeId(d.target)
+
+	switch d.kind {
+	case relationExtends:
+		return target + " <|-- " + source
+	case relationEmbeds:
+		arrow := " *.. "
+		if d.pointer {
+			arrow = " o.. "
+		}
+		return source + arrow + target + this.labelToMermaid("", d.label, d.stereotype)
+	}
+
+	result := source + " " + mermaidArrows[d.kind] + " "
+	if d.multiplicity != "" {
+		result += "\"" + d.multiplicity + "\" "
+	}
+
+	return result + target + this.labelToMermaid(d.qualifier, d.label, d.stereotype)
+}
+
+func (this *mermaidRenderer) labelToMermaid(qualifier string, label string, stereotype string) string {
+
+	parts := []string{}
+	if qualifier != "" {
+		parts = append(parts, "["+qualifier+"]")
+	}
+	if label != "" {
+		parts = append(parts, label)
+	}
+	if stereotype != "" {
+		parts = append(parts, "«"+stereotype+"»")
+	}
+
+	if len(parts) == 0 {
+		return ""
+	}
+
+	return " : " + mermaidEscape(strings.Join(parts, " "))
+}
+
+func (this *mermaidRenderer) implToMermaid(interfaceMeta1 *interfaceMeta, interfaceImpl1 *interfaceImpl) string {
+	result := this.nodeId(interfaceMeta1) + " <|.. " + this.nodeId(interfaceImpl1)
+	if interfaceImpl1.PointerOnly {
+		result += " : «pointer»"
+	}
+	return result
+}
+
+/**
+ * 包的import关系图, 使用flowchart, 标准库, vendor和外部模块使用不同的颜色
+ */
+func (this *mermaidRenderer) renderPackages() string {
+
+	result := "flowchart LR\n"
+
+	for _, node := range this.tool.shownPackageNodes() {
+		result += "  " + safeId(node.Name) + "[\"" + mermaidEscape(node.Name) + "\"]"
+		if node.Kind != packageInternal {
+			result += ":::" + node.Kind
+		}
+		result += "\n"
+	}
+
+	for index, edge := range this.tool.shownPackageEdges() {
+		result += fmt.Sprintf("  %s -->|%d| %s\n", safeId(edge.source.Name), edge.Weight, safeId(edge.target.Name))
+		if this.tool.highlightCycle(edge.cycle) {
+			result += fmt.Sprintf("  linkStyle %d stroke:#ff0000\n", index)
+		}
+	}
+
+	result += "  classDef stdlib fill:#eeeeee\n" +
+		"  classDef vendor fill:#fff3cd\n" +
+		"  classDef external fill:#dde8f6\n"
+
+	return result
+}
@@ -0,0 +1,250 @@
+package codeanalysis
+
+import (
+	"go/ast"
+	"go/types"
+)
+
+// 方法信息
+type methodMeta struct {
+	Name string
+	// 方法签名, 参数和返回值使用包含包路径的类型, 例如 Add(github.com/maobuji/list-interface.A)int
+	Sign string
+	// 接收者是否为指针, interface的方法始终为false
+	PointerReceiver bool
+	// 参数, 例如 a int, opts ...Option
+	Params string
+	// 返回值, 例如 error, (n int, err error)
+	Results string
+}
+
+// 匿名嵌入的类型
+type embedMeta struct {
+	// 嵌入类型所在的包路径, 无法确定时为空
+	PackagePath string
+	Name        string
+	// 是否以*T的方式嵌入
+	Pointer bool
+	// 源码中的嵌入类型, 例如 io.Reader
+	TypeString string
+}
+
+// interface的实现关系
+type interfaceImpl struct {
+	*structMeta
+	// 只有*T实现了interface, T没有实现
+	PointerOnly bool
+}
+
+func (this *analysisTool) createEmbedMeta(t ast.Expr) *embedMeta {
+
+	embedMeta1 := &embedMeta{
+		TypeString: this.typeToString(t, false),
+	}
+
+	starExpr, ok := t.(*ast.StarExpr)
+	if ok {
+		embedMeta1.Pointer = true
+		t = starExpr.X
+	}
+
+	t = genericBaseType(t)
+
+	if packagePath, name, ok := this.resolveTypeExpr(t); ok {
+		embedMeta1.PackagePath, embedMeta1.Name = this.followTypeAlias(packagePath, name)
+		return embedMeta1
+	}
+
+	switch expr := t.(type) {
+	case *ast.Ident:
+		embedMeta1.Name = expr.Name
+		if !this.isGoBaseType(expr.Name) {
+			embedMeta1.PackagePath = this.findPackagePathByAlias("", expr.Name)
+		}
+	case *ast.SelectorExpr:
+		embedMeta1.Name = expr.Sel.Name
+		embedMeta1.PackagePath = this.findPackagePathByAlias(this.selectorExprToString(expr.X), expr.Sel.Name)
+	default:
+		embedMeta1.Name = this.typeToString(t, false)
+	}
+
+	embedMeta1.PackagePath, embedMeta1.Name = this.followTypeAlias(embedMeta1.PackagePath, embedMeta1.Name)
+
+	return embedMeta1
+}
+
+/**
+ * interface的方法集, 包含嵌入interface的方法, key为方法名, value为方法签名
+ * 嵌入了无法解析的interface时complete为false
+ */
+func (this *analysisTool) interfaceMethodSet(interfaceMeta1 *interfaceMeta) (methods map[string]string, complete bool) {
+	methods = map[string]string{}
+	complete = this.collectInterfaceMethods(interfaceMeta1, methods, map[*interfaceMeta]bool{})
+	return
+}
+
+func (this *analysisTool) collectInterfaceMethods(interfaceMeta1 *interfaceMeta, methods map[string]string, visited map[*interfaceMeta]bool) bool {
+
+	if visited[interfaceMeta1] {
+		return true
+	}
+	visited[interfaceMeta1] = true
+
+	complete := true
+
+	for _, method := range interfaceMeta1.Methods {
+		methods[method.Name] = method.Sign
+	}
+
+	for _, embed := range interfaceMeta1.Embeds {
+		embedInterface := this.findInterfaceMeta(embed.PackagePath, embed.Name)
+		if embedInterface == nil {
+			complete = false
+			continue
+		}
+		if !this.collectInterfaceMethods(embedInterface, methods, visited) {
+			complete = false
+		}
+	}
+
+	return complete
+}
+
+// 计算方法集时, 某一嵌入深度上的类型
+type methodSetEntry struct {
+	structMeta    *structMeta
+	interfaceMeta *interfaceMeta
+	// 是否可以调用指针接收者的方法, 即外层为*T或者以*T的方式嵌入
+	addressable bool
+}
+
+/**
+ * struct的方法集, pointer为true时计算*T的方法集
+ * 嵌入类型的方法按嵌入深度提升, 较浅的同名方法优先, 同一深度出现多个同名方法时都不提升
+ */
+func (this *analysisTool) structMethodSet(structMeta1 *structMeta, pointer bool) map[string]string {
+
+	methods := map[string]string{}
+	blocked := map[string]bool{}
+	visited := map[interface{}]bool{}
+
+	level := []*methodSetEntry{{structMeta: structMeta1, addressable: pointer}}
+
+	for len(level) > 0 {
+
+		// 当前深度的方法名, 对应的方法签名, 不在方法集中的方法签名为空
+		found := map[string][]string{}
+		next := []*methodSetEntry{}
+
+		for _, entry := range level {
+
+			if entry.structMeta != nil {
+
+				if visited[entry.structMeta] {
+					continue
+				}
+				visited[entry.structMeta] = true
+
+				for _, method := range entry.structMeta.Methods {
+					if method.PointerReceiver && !entry.addressable {
+						found[method.Name] = append(found[method.Name], "")
+					} else {
+						found[method.Name] = append(found[method.Name], method.Sign)
+					}
+				}
+
+				for _, embed := range entry.structMeta.Embeds {
+					nextEntry := &methodSetEntry{
+						structMeta:    this.findStruct(embed.PackagePath, embed.Name),
+						interfaceMeta: this.findInterfaceMeta(embed.PackagePath, embed.Name),
+						addressable:   entry.addressable || embed.Pointer,
+					}
+					if nextEntry.structMeta != nil || nextEntry.interfaceMeta != nil {
+						next = append(next, nextEntry)
+					}
+				}
+
+			} else {
+
+				if visited[entry.interfaceMeta] {
+					continue
+				}
+				visited[entry.interfaceMeta] = true
+
+				interfaceMethods, _ := this.interfaceMethodSet(entry.interfaceMeta)
+				for name, sign := range interfaceMethods {
+					found[name] = append(found[name], sign)
+				}
+			}
+		}
+
+		for name, signs := range found {
+			if blocked[name] || mapContains(methods, name) {
+				continue
+			}
+			if len(signs) > 1 {
+				blocked[name] = true
+				continue
+			}
+			if signs[0] != "" {
+				methods[name] = signs[0]
+			} else {
+				blocked[name] = true
+			}
+		}
+
+		level = next
+	}
+
+	return methods
+}
+
+/**
+ * 方法集methods是否包含了interface的所有方法
+ */
+func methodSetContains(methods map[string]string, interfaceMethods map[string]string) bool {
+	for name, sign := range interfaceMethods {
+		if methods[name] != sign {
+			return false
+		}
+	}
+	return true
+}
+
+/**
+ * 类型检查模式下, 使用go/types判断struct是否实现了interface, 没有类型信息时ok为false
+ */
+func (this *analysisTool) typesImplements(structMeta1 *structMeta, interfaceMeta1 *interfaceMeta) (implemented bool, pointerOnly bool, ok bool) {
+
+	structObject := this.lookupTypeObject(structMeta1.PackagePath, structMeta1.Name)
+	interfaceObject := this.lookupTypeObject(interfaceMeta1.PackagePath, interfaceMeta1.Name)
+	if structObject == nil || interfaceObject == nil {
+		return
+	}
+
+	interfaceType, isInterface := interfaceObject.Type().Underlying().(*types.Interface)
+	if !isInterface {
+		return
+	}
+
+	structType := structObject.Type()
+	if named, isNamed := structType.(*types.Named); isNamed && named.TypeParams().Len() > 0 {
+		return
+	}
+
+	ok = true
+
+	// 空interface和约束interface不画实现关系
+	if interfaceType.NumMethods() == 0 || !interfaceType.IsMethodSet() {
+		return
+	}
+
+	if types.Implements(structType, interfaceType) {
+		implemented = true
+	} else if types.Implements(types.NewPointer(structType), interfaceType) {
+		implemented = true
+		pointerOnly = true
+	}
+
+	return
+}
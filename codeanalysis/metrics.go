@@ -0,0 +1,183 @@
+package codeanalysis
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// 包的耦合度和抽象度, 按照类型之间的关系计算, 不包括实现关系
+type PackageMetrics struct {
+	Package string `json:"package"`
+	// struct, interface和有方法的命名类型的数量, 不包括类型别名
+	Types      int `json:"types"`
+	Interfaces int `json:"interfaces"`
+	Methods    int `json:"methods"`
+	Fields     int `json:"fields"`
+	// 传入耦合, 其他包中依赖本包类型的类型数量
+	Afferent int `json:"afferent"`
+	// 传出耦合, 本包的类型依赖的其他包中的类型数量
+	Efferent int `json:"efferent"`
+	// 不稳定性, Ce / (Ca + Ce), 没有耦合时为0
+	Instability float64 `json:"instability"`
+	// 抽象度, interface数量 / 类型数量
+	Abstractness float64 `json:"abstractness"`
+	// 到主序列的距离, |A + I - 1|
+	Distance float64 `json:"distance"`
+}
+
+var metricsColumns = []string{"package", "types", "interfaces", "methods", "fields",
+	"afferent", "efferent", "instability", "abstractness", "distance"}
+
+/**
+ * 每个包的度量, 按照包路径排序, 不包括测试中的类型
+ */
+func (this *analysisTool) Metrics() []*PackageMetrics {
+
+	metricsMap := map[string]*PackageMetrics{}
+	find := func(packagePath string) *PackageMetrics {
+		metrics, ok := metricsMap[packagePath]
+		if !ok {
+			metrics = &PackageMetrics{Package: packagePath}
+			metricsMap[packagePath] = metrics
+		}
+		return metrics
+	}
+
+	for _, structMeta1 := range this.structMetas {
+		if this.isTestType(structMeta1) {
+			continue
+		}
+		metrics := find(structMeta1.PackagePath)
+		metrics.Types++
+		metrics.Methods += len(structMeta1.Methods)
+		metrics.Fields += len(structMeta1.Fields)
+	}
+
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		if this.isTestType(interfaceMeta1) {
+			continue
+		}
+		metrics := find(interfaceMeta1.PackagePath)
+		metrics.Types++
+		metrics.Interfaces++
+		metrics.Methods += len(interfaceMeta1.Methods)
+	}
+
+	// 同一对类型之间的多个关系只计算一次
+	afferentTypes := map[string]map[typeMeta]bool{}
+	efferentTypes := map[string]map[typeMeta]bool{}
+
+	for _, d := range this.dependencyRelations {
+
+		sourcePackagePath := typeMetaPackagePath(d.source)
+		targetPackagePath := typeMetaPackagePath(d.target)
+
+		if sourcePackagePath == targetPackagePath || this.isTestType(d.source) {
+			continue
+		}
+
+		if afferentTypes[targetPackagePath] == nil {
+			afferentTypes[targetPackagePath] = map[typeMeta]bool{}
+		}
+		afferentTypes[targetPackagePath][d.source] = true
+
+		if efferentTypes[sourcePackagePath] == nil {
+			efferentTypes[sourcePackagePath] = map[typeMeta]bool{}
+		}
+		efferentTypes[sourcePackagePath][d.target] = true
+	}
+
+	packagePaths := []string{}
+	for packagePath := range metricsMap {
+		packagePaths = append(packagePaths, packagePath)
+	}
+	sort.Strings(packagePaths)
+
+	result := []*PackageMetrics{}
+
+	for _, packagePath := range packagePaths {
+
+		metrics := metricsMap[packagePath]
+		metrics.Afferent = len(afferentTypes[packagePath])
+		metrics.Efferent = len(efferentTypes[packagePath])
+
+		if metrics.Afferent+metrics.Efferent > 0 {
+			metrics.Instability = float64(metrics.Efferent) / float64(metrics.Afferent+metrics.Efferent)
+		}
+		if metrics.Types > 0 {
+			metrics.Abstractness = float64(metrics.Interfaces) / float64(metrics.Types)
+		}
+		metrics.Distance = math.Abs(metrics.Abstractness + metrics.Instability - 1)
+
+		metrics.Instability = roundMetric(metrics.Instability)
+		metrics.Abstractness = roundMetric(metrics.Abstractness)
+		metrics.Distance = roundMetric(metrics.Distance)
+
+		result = append(result, metrics)
+	}
+
+	return result
+}
+
+func roundMetric(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func (this *PackageMetrics) values() []string {
+	return []string{
+		this.Package,
+		strconv.Itoa(this.Types),
+		strconv.Itoa(this.Interfaces),
+		strconv.Itoa(this.Methods),
+		strconv.Itoa(this.Fields),
+		strconv.Itoa(this.Afferent),
+		strconv.Itoa(this.Efferent),
+		strconv.FormatFloat(this.Instability, 'f', 2, 64),
+		strconv.FormatFloat(this.Abstractness, 'f', 2, 64),
+		strconv.FormatFloat(this.Distance, 'f', 2, 64),
+	}
+}
+
+/**
+ * 对齐的文本表格, 适合输出到终端
+ */
+func MetricsTable(metrics []*PackageMetrics) string {
+
+	buffer := &bytes.Buffer{}
+	writer := tabwriter.NewWriter(buffer, 0, 4, 2, ' ', 0)
+
+	// 中文在终端中占两个字符宽度, 表头使用英文才能对齐
+	fmt.Fprintln(writer, "package\ttypes\tinterfaces\tmethods\tfields\tCa\tCe\tI\tA\tD")
+	for _, packageMetrics := range metrics {
+		fmt.Fprintln(writer, strings.Join(packageMetrics.values(), "\t"))
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
+
+func MetricsCSV(metrics []*PackageMetrics) string {
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+
+	writer.Write(metricsColumns)
+	for _, packageMetrics := range metrics {
+		writer.Write(packageMetrics.values())
+	}
+	writer.Flush()
+
+	return buffer.String()
+}
+
+func MetricsJSON(metrics []*PackageMetrics) string {
+	content, _ := json.MarshalIndent(metrics, "", "  ")
+	return string(content) + "\n"
+}
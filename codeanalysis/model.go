@@ -0,0 +1,154 @@
+package codeanalysis
+
+// 类图中的成员, 字段, 方法, 枚举值或interface中嵌入的interface
+type memberMeta struct {
+	// 可见性, +为导出, -为未导出, 枚举值和嵌入的interface为空
+	Visibility string
+	// 成员名, 匿名嵌入的字段和嵌入的interface为空
+	Name string
+	// 字段类型, 例如 map[string]sub2.Sub2A, 方法为空
+	Type   string
+	Method bool
+	// 方法的参数, 例如 a int, opts ...Option
+	Params string
+	// 方法的返回值, 例如 error, (n int, err error)
+	Results string
+	// 指针接收者的方法
+	PointerReceiver bool
+}
+
+// 类图中的一组成员, 第一组为类型自己声明的成员, 后面是嵌入类型提升的成员
+type memberGroup struct {
+	// 分组标题, 例如 embedded Reader, 第一组为空
+	Title   string
+	Members []*memberMeta
+}
+
+func (this *fieldMeta) member() *memberMeta {
+	if this.Embedded {
+		return &memberMeta{Visibility: visibilityToUML(this.Name), Type: this.Type}
+	}
+	return &memberMeta{Visibility: visibilityToUML(this.Name), Name: this.Name, Type: this.Type}
+}
+
+func (this *methodMeta) member() *memberMeta {
+	return &memberMeta{
+		Visibility:      visibilityToUML(this.Name),
+		Name:            this.Name,
+		Method:          true,
+		Params:          this.Params,
+		Results:         this.Results,
+		PointerReceiver: this.PointerReceiver,
+	}
+}
+
+/**
+ * 类图节点中显示的成员, 按照Detail过滤, 超过MaxMembers的成员不返回, hidden为没有返回的成员数量
+ * 没有成员的分组不返回, Focus的边界类型没有成员
+ */
+func (this *analysisTool) classMembers(node typeMeta) (groups []*memberGroup, hidden int) {
+
+	if this.isStub(node) {
+		return
+	}
+
+	own := &memberGroup{}
+	all := []*memberGroup{own}
+
+	switch meta := node.(type) {
+	case *structMeta:
+		for _, name := range meta.CollapsedTypes {
+			own.Members = append(own.Members, &memberMeta{Name: name})
+		}
+		if meta.UnderlyingType == "" {
+			for _, field := range meta.Fields {
+				if this.showField(field.Name) {
+					own.Members = append(own.Members, field.member())
+				}
+			}
+		}
+		for _, value := range meta.EnumValues {
+			if this.showField(value) || this.config.Detail == DetailSignatures {
+				own.Members = append(own.Members, &memberMeta{Name: value})
+			}
+		}
+		own.Members = append(own.Members, this.methodMembers(meta.Methods)...)
+		if meta.UnderlyingType == "" && this.config.ShowPromoted {
+			all = append(all, this.promotedMembers(meta)...)
+		}
+	case *interfaceMeta:
+		for _, embed := range meta.Embeds {
+			if this.showMethod(embed.Name) {
+				own.Members = append(own.Members, &memberMeta{Type: embed.TypeString})
+			}
+		}
+		own.Members = append(own.Members, this.methodMembers(meta.Methods)...)
+		// interface始终列出嵌入的interface中的方法
+		all = append(all, this.promotedMembers(meta)...)
+	}
+
+	count := 0
+
+	for _, group := range all {
+
+		shown := &memberGroup{Title: group.Title}
+
+		for _, member := range group.Members {
+			if this.config.MaxMembers > 0 && count >= this.config.MaxMembers {
+				hidden++
+				continue
+			}
+			shown.Members = append(shown.Members, member)
+			count++
+		}
+
+		if len(shown.Members) > 0 {
+			groups = append(groups, shown)
+		}
+	}
+
+	return
+}
+
+func (this *analysisTool) methodMembers(methods []*methodMeta) []*memberMeta {
+	members := []*memberMeta{}
+	for _, method := range methods {
+		if this.showMethod(method.Name) {
+			members = append(members, method.member())
+		}
+	}
+	return members
+}
+
+/**
+ * 类型别名最终指向的类图节点, 不在分析结果中时返回nil
+ */
+func (this *analysisTool) typeAliasTarget(typeAliasMeta1 *typeAliasMeta) typeMeta {
+
+	targetPackagePath, targetName := this.followTypeAlias(typeAliasMeta1.PackagePath, typeAliasMeta1.Name)
+
+	if structMeta1 := this.findStruct(targetPackagePath, targetName); structMeta1 != nil {
+		return structMeta1
+	}
+	if interfaceMeta1 := this.findInterfaceMeta(targetPackagePath, targetName); interfaceMeta1 != nil {
+		return interfaceMeta1
+	}
+
+	return nil
+}
+
+/**
+ * 图中显示的类型别名, 只有使用ShowTypeAliases时才显示
+ */
+func (this *analysisTool) shownTypeAliases() []*typeAliasMeta {
+	result := []*typeAliasMeta{}
+	if !this.config.ShowTypeAliases {
+		return result
+	}
+	for _, typeAliasMeta1 := range this.typeAliasMetas {
+		if typeAliasMeta1.IsAlias && this.showType(typeAliasMeta1) {
+			result = append(result, typeAliasMeta1)
+		}
+	}
+	return result
+}
@@ -0,0 +1,154 @@
+package codeanalysis
+
+import (
+	"go/token"
+	"path"
+	"strings"
+)
+
+// 图的内容
+const (
+	// 类型和类型之间的关系
+	ViewTypes = "types"
+	// 包和包之间的import关系
+	ViewPackages = "packages"
+)
+
+var views = []string{ViewTypes, ViewPackages}
+
+// 包的种类, 标准库, vendor和外部模块也是Collapse的可选值
+const (
+	// 代码目录中的包
+	packageInternal = "internal"
+	PackageStdlib   = "stdlib"
+	PackageVendor   = "vendor"
+	PackageExternal = "external"
+)
+
+var collapsiblePackages = []string{PackageStdlib, PackageVendor, PackageExternal}
+
+// 包依赖图中的节点
+type packageNode struct {
+	// 包路径, 合并后为 stdlib, vendor 或 external
+	Name string
+	// 包的种类, internal, stdlib, vendor, external
+	Kind string
+}
+
+// 包依赖图中的边, source中的文件import了target
+type packageEdge struct {
+	source *packageNode
+	target *packageNode
+	// import了target的文件数量
+	Weight int
+	// 每个文件中import语句的位置
+	positions []token.Position
+	// 是否在循环依赖中
+	cycle bool
+}
+
+/**
+ * 记录当前文件所在的包import的包, 同一个文件import合并后的同一个节点只计算一次
+ */
+func (this *analysisTool) visitPackageImports(imports []*importMeta) {
+
+	dir := path.Dir(this.currentFile)
+
+	sourceKind := packageInternal
+	if this.isVendorDir(dir) {
+		sourceKind = PackageVendor
+	}
+	source := this.addPackageNode(this.currentPackagePath, sourceKind)
+
+	targets := []*packageNode{}
+	positions := []token.Position{}
+
+	for _, import1 := range imports {
+		target := this.addPackageNode(import1.Path, this.packageKind(dir, import1.Path))
+		if target == source || packageNodeSliceContains(targets, target) {
+			continue
+		}
+		targets = append(targets, target)
+		positions = append(positions, import1.Position)
+	}
+
+	for index, target := range targets {
+		edge := this.findPackageEdge(source, target)
+		if edge == nil {
+			edge = &packageEdge{source: source, target: target}
+			this.packageEdges = append(this.packageEdges, edge)
+		}
+		edge.Weight++
+		edge.positions = append(edge.positions, positions[index])
+	}
+}
+
+/**
+ * 在fromDir目录的go文件中import的包的种类, 按照源码目录判断, 找不到源码目录时只区分标准库和外部模块
+ */
+func (this *analysisTool) packageKind(fromDir string, packagePath string) string {
+
+	dir := this.findPackageDirFrom(fromDir, packagePath)
+
+	if dir != "" && this.isVendorDir(dir) {
+		return PackageVendor
+	}
+
+	if dir != "" && (dir == this.config.CodeDir || strings.HasPrefix(dir, this.config.CodeDir+"/")) &&
+		!inSomeDir(dir, this.config.IgnoreDirs) {
+		return packageInternal
+	}
+
+	if sliceContains(stdlibs, packagePath) || !strings.Contains(strings.Split(packagePath, "/")[0], ".") {
+		return PackageStdlib
+	}
+
+	return PackageExternal
+}
+
+func (this *analysisTool) isVendorDir(dir string) bool {
+	if this.config.VendorDir != "" && strings.HasPrefix(dir, this.config.VendorDir) {
+		return true
+	}
+	return strings.Contains(dir, "/vendor/")
+}
+
+/**
+ * 添加包依赖图中的节点, 需要合并的种类使用种类名做为节点名
+ */
+func (this *analysisTool) addPackageNode(packagePath string, kind string) *packageNode {
+
+	name := packagePath
+	if sliceContains(this.config.CollapsePackages, kind) {
+		name = kind
+	}
+
+	for _, node := range this.packageNodes {
+		if node.Name == name {
+			return node
+		}
+	}
+
+	node := &packageNode{Name: name, Kind: kind}
+	this.packageNodes = append(this.packageNodes, node)
+
+	return node
+}
+
+func (this *analysisTool) findPackageEdge(source *packageNode, target *packageNode) *packageEdge {
+	for _, edge := range this.packageEdges {
+		if edge.source == source && edge.target == target {
+			return edge
+		}
+	}
+	return nil
+}
+
+func packageNodeSliceContains(src []*packageNode, value *packageNode) bool {
+	for _, srcValue := range src {
+		if srcValue == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,310 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlantUML格式的类图
+type plantUMLRenderer struct {
+	tool *analysisTool
+}
+
+func newPlantUMLRenderer(tool *analysisTool) *plantUMLRenderer {
+	return &plantUMLRenderer{tool: tool}
+}
+
+var plantUMLArrows = map[string]string{
+	relationComposition: "*--",
+	relationAggregation: "o--",
+	relationAssociation: "-->",
+	relationDependency:  "..>",
+}
+
+func (this *plantUMLRenderer) render() string {
+
+	if this.tool.config.View == ViewPackages {
+		return this.renderPackages()
+	}
+
+	uml := ""
+	layers := []string{}
+
+	for _, structMeta1 := range this.tool.structMetas {
+		if this.tool.showType(structMeta1) {
+			uml += this.nodeToUML(structMeta1)
+			uml += "\n"
+			layers = appendLayer(layers, this.tool.layerOf(structMeta1))
+		}
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if this.tool.showType(interfaceMeta1) {
+			uml += this.nodeToUML(interfaceMeta1)
+			uml += "\n"
+			layers = appendLayer(layers, this.tool.layerOf(interfaceMeta1))
+		}
+	}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		uml += this.nodeToUML(typeAliasMeta1)
+		uml += "\n"
+	}
+
+	for _, d := range this.tool.shownRelations() {
+		uml += this.relationToUML(d)
+		uml += "\n"
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if !this.tool.showType(interfaceMeta1) {
+			continue
+		}
+		interfaceImpls := this.tool.findInterfaceImpls(interfaceMeta1)
+		for _, interfaceImpl1 := range interfaceImpls {
+			if this.tool.showRelation(interfaceImpl1, interfaceMeta1) {
+				uml += this.implToUML(interfaceMeta1, interfaceImpl1)
+			}
+		}
+	}
+
+	// 生成的类型和测试中的类型在包的子namespace中, 类型也使用分组的颜色
+	if len(layers) > 0 {
+		skinparam := "skinparam class {\n"
+		for _, layer := range layers {
+			skinparam += fmt.Sprintf("  BackgroundColor<<%s>> %s\n  BorderColor<<%s>> %s\n", layer, layerColors[layer][0], layer, layerColors[layer][1])
+		}
+		uml = skinparam + "}\n" + uml
+	}
+
+	return "@startuml\n" + uml + "@enduml"
+}
+
+func appendLayer(layers []string, layer string) []string {
+	if layer == "" || sliceContains(layers, layer) {
+		return layers
+	}
+	return append(layers, layer)
+}
+
+/**
+ * 类图节点, 放在包路径对应的namespace中, 生成的类型和测试中的类型放在子namespace中, 类型别名指向的类型在图中时再画一条 ..> 依赖线
+ */
+func (this *plantUMLRenderer) nodeToUML(node typeMeta) string {
+
+	classUML := ""
+	suffix := ""
+
+	if this.tool.isStub(node) {
+		return this.namespaceToUML(node, this.stubToUML(node))
+	}
+
+	stereotypes := ""
+	if layer := this.tool.layerOf(node); layer != "" {
+		stereotypes = " <<" + layer + ">>"
+	}
+	if platform := this.tool.platformOf(node); platform != "" {
+		stereotypes += " <<" + platform + ">>"
+	}
+
+	switch meta := node.(type) {
+	case *structMeta:
+		switch {
+		case len(meta.EnumValues) > 0:
+			classUML = "enum " + meta.Name + stereotypes + " {\n"
+		case meta.UnderlyingType != "":
+			classUML = "class " + meta.Name + typeParamsToUML(meta.TypeParams) + stereotypes + " <<" + meta.UnderlyingType + ">> {\n"
+		default:
+			classUML = "class " + meta.Name + typeParamsToUML(meta.TypeParams) + stereotypes + " {\n"
+		}
+		classUML += this.membersToUML(node) + "}"
+	case *interfaceMeta:
+		stereotype := stereotypes
+		if len(meta.TypeUnion) > 0 {
+			// 类型约束interface, 例如 ~int | ~string
+			stereotype += " <<" + strings.Join(meta.TypeUnion, "; ") + ">>"
+		}
+		classUML = "interface " + meta.Name + typeParamsToUML(meta.TypeParams) + stereotype + "  {\n" + this.membersToUML(node) + "}"
+	case *typeAliasMeta:
+		target := this.tool.typeAliasTarget(meta)
+		if target == nil {
+			classUML = fmt.Sprintf("class %s%s <<alias %s>> {\n}", meta.Name, stereotypes, meta.targetTypeName)
+		} else {
+			classUML = fmt.Sprintf("class %s%s <<alias>> {\n}", meta.Name, stereotypes)
+			suffix = fmt.Sprintf("\n%s ..> %s : alias", this.nameOf(meta), this.nameOf(target))
+		}
+	}
+
+	return this.namespaceToUML(node, classUML) + suffix
+}
+
+/**
+ * 节点所在的namespace, 单独分组的类型放在包的子namespace中, 例如 github.com\\a\\b.generated, github.com\\a\\b.test
+ */
+func (this *plantUMLRenderer) namespaceOf(node typeMeta) string {
+	namespace := packagePathToUML(typeMetaPackagePath(node))
+	if layer := this.tool.layerOf(node); layer != "" {
+		namespace += "." + layer
+	}
+	return namespace
+}
+
+/**
+ * 关系中引用的节点全名, 和节点所在的namespace一致
+ */
+func (this *plantUMLRenderer) nameOf(node typeMeta) string {
+	return this.namespaceOf(node) + "." + typeMetaName(node)
+}
+
+/**
+ * 单独分组的namespace使用分组的背景色, 例如 namespace github.com\\a\\b.generated #f0f0f0 { ... }
+ */
+func (this *plantUMLRenderer) namespaceToUML(node typeMeta, classUML string) string {
+	namespace := this.namespaceOf(node)
+	if layer := this.tool.layerOf(node); layer != "" {
+		namespace += " " + layerColors[layer][0]
+	}
+	return fmt.Sprintf("namespace %s {\n %s \n}", namespace, classUML)
+}
+
+/**
+ * 边界类型, 只有类型名, 例如 class User <<stub>> {}
+ */
+func (this *plantUMLRenderer) stubToUML(node typeMeta) string {
+	keyword := "class"
+	switch meta := node.(type) {
+	case *structMeta:
+		if len(meta.EnumValues) > 0 {
+			keyword = "enum"
+		}
+	case *interfaceMeta:
+		keyword = "interface"
+	}
+	if this.tool.isExcluded(node) {
+		// 排除的类型显示为灰色
+		return keyword + " " + typeMetaName(node) + " <<stub>> #eeeeee {\n}"
+	}
+	return keyword + " " + typeMetaName(node) + " <<stub>> {\n}"
+}
+
+/**
+ * 节点的成员, 嵌入类型提升的成员用 .. embedded X .. 分隔, 超过MaxMembers的部分显示为 ... N more
+ */
+func (this *plantUMLRenderer) membersToUML(node typeMeta) string {
+
+	groups, hidden := this.tool.classMembers(node)
+
+	result := ""
+
+	for _, group := range groups {
+		if group.Title != "" {
+			result += "  .. " + group.Title + " ..\n"
+		}
+		for _, member := range group.Members {
+			result += "  " + this.memberToUML(member) + "\n"
+		}
+	}
+
+	if hidden > 0 {
+		result += fmt.Sprintf("  ... %d more\n", hidden)
+	}
+
+	return result
+}
+
+/**
+ * 成员, 例如 -a int, +Read(p []byte) (n int, err error), 指针接收者的方法加上 <<pointer>>
+ */
+func (this *plantUMLRenderer) memberToUML(member *memberMeta) string {
+
+	if !member.Method {
+		return member.Visibility + strings.TrimSpace(member.Name+" "+member.Type)
+	}
+
+	uml := member.Visibility + member.Name + "(" + member.Params + ")"
+	if member.Results != "" {
+		uml += " " + member.Results
+	}
+	if member.PointerReceiver {
+		uml += " <<pointer>>"
+	}
+
+	return uml
+}
+
+func (this *plantUMLRenderer) relationToUML(d *DependencyRelation) string {
+
+	switch d.kind {
+	case relationExtends:
+		return this.nameOf(d.target) + " " + this.arrowToUML("<|--", d.cycle) + " " + this.nameOf(d.source)
+	case relationEmbeds:
+		arrow := "*.."
+		if d.pointer {
+			arrow = "o.."
+		}
+		return this.nameOf(d.source) + " " + this.arrowToUML(arrow, d.cycle) + " " + this.nameOf(d.target) + this.labelToUML(d.label, d.stereotype)
+	}
+
+	uml := this.nameOf(d.source)
+	if d.qualifier != "" {
+		uml += " [" + d.qualifier + "]"
+	}
+	uml += " " + this.arrowToUML(plantUMLArrows[d.kind], d.cycle) + " "
+	if d.multiplicity != "" {
+		uml += "\"" + d.multiplicity + "\" "
+	}
+	uml += this.nameOf(d.target) + this.labelToUML(d.label, d.stereotype)
+
+	return uml
+}
+
+/**
+ * 循环依赖中的边使用红色, 颜色写在箭头中间, 例如 *-[#red]-, .[#red].>
+ */
+func (this *plantUMLRenderer) arrowToUML(arrow string, cycle bool) string {
+	if !this.tool.highlightCycle(cycle) {
+		return arrow
+	}
+	index := strings.IndexAny(arrow, "-.") + 1
+	return arrow[:index] + "[#red]" + arrow[index:]
+}
+
+func (this *plantUMLRenderer) labelToUML(label string, stereotype string) string {
+	if stereotype != "" {
+		label += " <<" + stereotype + ">>"
+	}
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return ""
+	}
+	return " : " + label
+}
+
+func (this *plantUMLRenderer) implToUML(interfaceMeta1 *interfaceMeta, interfaceImpl1 *interfaceImpl) string {
+	if interfaceImpl1.PointerOnly {
+		return fmt.Sprintf("%s <|- %s : <<pointer>>\n", this.nameOf(interfaceMeta1), this.nameOf(interfaceImpl1.structMeta))
+	}
+	return fmt.Sprintf("%s <|- %s\n", this.nameOf(interfaceMeta1), this.nameOf(interfaceImpl1.structMeta))
+}
+
+/**
+ * 包的import关系图, 每个包画成package, 边上显示import的文件数量
+ */
+func (this *plantUMLRenderer) renderPackages() string {
+
+	uml := ""
+
+	for _, node := range this.tool.shownPackageNodes() {
+		stereotype := ""
+		if node.Kind != packageInternal {
+			stereotype = " <<" + node.Kind + ">>"
+		}
+		uml += fmt.Sprintf("package \"%s\" as %s%s {\n}\n", node.Name, safeId(node.Name), stereotype)
+	}
+
+	for _, edge := range this.tool.shownPackageEdges() {
+		uml += fmt.Sprintf("%s %s %s : %d\n", safeId(edge.source.Name), this.arrowToUML("..>", edge.cycle), safeId(edge.target.Name), edge.Weight)
+	}
+
+	return "@startuml\n" + uml + "@enduml"
+}
@@ -0,0 +1,148 @@
+package codeanalysis
+
+import (
+	"bufio"
+	"go/build"
+	"go/build/constraint"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// 文件名后缀中可以使用的操作系统和CPU架构, 和go命令相同
+var knownOS = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js",
+	"linux", "nacl", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos"}
+
+var knownArch = []string{"386", "amd64", "amd64p32", "arm", "armbe", "arm64", "arm64be", "loong64", "mips",
+	"mipsle", "mips64", "mips64le", "mips64p32", "mips64p32le", "ppc", "ppc64", "ppc64le", "riscv", "riscv64",
+	"s390", "s390x", "sparc", "sparc64", "wasm"}
+
+/**
+ * 选择go文件的构建环境, GOOS和GOARCH为空时使用当前环境, 交叉编译时和go命令一样不启用cgo
+ */
+func newBuildContext(config Config) build.Context {
+
+	context := build.Default
+
+	if config.GOOS != "" {
+		context.GOOS = config.GOOS
+	}
+	if config.GOARCH != "" {
+		context.GOARCH = config.GOARCH
+	}
+	if context.GOOS != runtime.GOOS || context.GOARCH != runtime.GOARCH {
+		context.CgoEnabled = false
+	}
+
+	context.BuildTags = config.BuildTags
+
+	return context
+}
+
+/**
+ * 按照文件名后缀和 //go:build 判断文件是否参与构建, 读取文件失败时仍然解析
+ */
+func (this *analysisTool) matchBuildContext(file string) bool {
+
+	matched, err := this.buildContext.MatchFile(path.Dir(file), path.Base(file))
+	if err != nil {
+		log.Warnf("读取文件%s的构建约束失败, %s", file, err)
+		return true
+	}
+
+	if !matched {
+		log.Debugf("文件%s不满足构建约束, GOOS=%s, GOARCH=%s", file, this.buildContext.GOOS, this.buildContext.GOARCH)
+	}
+
+	return matched
+}
+
+/**
+ * 文件的构建约束, 文件名后缀和 //go:build 用 && 连接, 例如 linux && amd64, 没有构建约束时为空
+ */
+func (this *analysisTool) fileConstraint(file string) string {
+
+	if expr, ok := this.fileConstraints[file]; ok {
+		return expr
+	}
+
+	exprs := []string{}
+
+	// 文件名后缀, 例如 _linux.go, _windows_amd64.go, _arm64.go, 和go命令一样先去掉 _test, 例如 _linux_test.go
+	parts := strings.Split(strings.TrimSuffix(strings.TrimSuffix(path.Base(file), ".go"), "_test"), "_")
+	if n := len(parts); n >= 3 && sliceContains(knownOS, parts[n-2]) && sliceContains(knownArch, parts[n-1]) {
+		exprs = append(exprs, parts[n-2], parts[n-1])
+	} else if n >= 2 && (sliceContains(knownOS, parts[n-1]) || sliceContains(knownArch, parts[n-1])) {
+		exprs = append(exprs, parts[n-1])
+	}
+
+	if expr := readBuildConstraint(file); expr != nil {
+		text := expr.String()
+		if _, ok := expr.(*constraint.OrExpr); ok && len(exprs) > 0 {
+			text = "(" + text + ")"
+		}
+		exprs = append(exprs, text)
+	}
+
+	this.fileConstraints[file] = strings.Join(exprs, " && ")
+
+	return this.fileConstraints[file]
+}
+
+/**
+ * 读取package之前的 //go:build, 没有时使用旧的 // +build
+ */
+func readBuildConstraint(file string) constraint.Expr {
+
+	reader, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+
+	var plusBuild constraint.Expr
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+
+		if constraint.IsGoBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil {
+				return expr
+			}
+		}
+
+		if constraint.IsPlusBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil {
+				if plusBuild == nil {
+					plusBuild = expr
+				} else {
+					plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+				}
+			}
+		}
+	}
+
+	return plusBuild
+}
+
+/**
+ * ShowPlatform时类型所在文件的构建约束, 做为构造型显示
+ */
+func (this *analysisTool) platformOf(typeMeta1 typeMeta) string {
+	if !this.config.ShowPlatform {
+		return ""
+	}
+	file := typeMetaFilePath(typeMeta1)
+	if file == "" {
+		return ""
+	}
+	return this.fileConstraint(file)
+}
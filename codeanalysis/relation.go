@@ -0,0 +1,206 @@
+package codeanalysis
+
+import (
+	"go/ast"
+)
+
+// 字段类型的形状, 决定关系的箭头, 多重性和标签
+type fieldShape struct {
+	// 关系的类型, 值类型为组合, 指针为聚合, chan和func为关联
+	kind string
+	// 多重性, 切片和map为*, 数组为长度
+	multiplicity string
+	// map的key类型, 画成限定符
+	qualifier string
+	// chan和func类型字段的构造型
+	stereotype string
+	// func类型字段的函数签名
+	funcType *ast.FuncType
+}
+
+/**
+ * 根据字段类型的形状判断关系
+ * 值类型为组合 *--, 指针为聚合 o--, 切片和数组的多重性为*和长度, map的key做为限定符
+ * chan和func类型只是关联 -->, 分别加上<<chan>>和<<func>>
+ */
+func (this *analysisTool) fieldShape(t ast.Expr) *fieldShape {
+
+	shape := &fieldShape{}
+	pointer := false
+	aliasHops := 0
+
+	for t != nil {
+
+		switch expr := t.(type) {
+		case *ast.StarExpr:
+			pointer = true
+			t = expr.X
+			continue
+		case *ast.ParenExpr:
+			t = expr.X
+			continue
+		case *ast.ArrayType:
+			if shape.multiplicity == "" {
+				shape.multiplicity = arrayLenToUML(expr.Len)
+			}
+			pointer = false
+			t = expr.Elt
+			continue
+		case *ast.MapType:
+			if shape.qualifier == "" && shape.multiplicity == "" {
+				shape.qualifier = this.typeToString(expr.Key, false)
+			}
+			shape.multiplicity = "*"
+			pointer = false
+			t = expr.Value
+			continue
+		case *ast.ChanType:
+			shape.stereotype = "chan"
+			t = expr.Value
+			continue
+		case *ast.FuncType:
+			shape.stereotype = "func"
+			shape.funcType = expr
+		case *ast.Ident, *ast.SelectorExpr:
+			// 类型别名按照指向的类型判断, 例如 type PtrUser = *User
+			if targetExpr := this.aliasTargetExpr(expr); targetExpr != nil && aliasHops < 10 {
+				aliasHops++
+				t = targetExpr
+				continue
+			}
+		}
+
+		break
+	}
+
+	switch {
+	case shape.stereotype != "":
+		shape.kind = relationAssociation
+	case pointer:
+		shape.kind = relationAggregation
+	default:
+		shape.kind = relationComposition
+	}
+
+	return shape
+}
+
+func arrayLenToUML(length ast.Expr) string {
+	switch expr := length.(type) {
+	case *ast.BasicLit:
+		return expr.Value
+	case *ast.Ident:
+		return expr.Name
+	}
+	return "*"
+}
+
+/**
+ * 类型别名 type A = B 中B的类型表达式, 不是类型别名时返回nil
+ */
+func (this *analysisTool) aliasTargetExpr(t ast.Expr) ast.Expr {
+
+	packagePath := ""
+	name := ""
+
+	switch expr := t.(type) {
+	case *ast.Ident:
+		if this.isGoBaseType(expr.Name) || this.isTypeParam(expr.Name) {
+			return nil
+		}
+		packagePath = this.currentPackagePath
+		name = expr.Name
+	case *ast.SelectorExpr:
+		packagePath = this.findPackagePathByAlias(this.selectorExprToString(expr.X), expr.Sel.Name)
+		name = expr.Sel.Name
+	}
+
+	if resolvedPackagePath, _, ok := this.resolveTypeExpr(t); ok && resolvedPackagePath == "" {
+		return nil
+	}
+
+	typeAliasMeta1 := this.findTypeAlias(packagePath, name)
+	if typeAliasMeta1 == nil || !typeAliasMeta1.IsAlias {
+		return nil
+	}
+
+	return typeAliasMeta1.targetExpr
+}
+
+/**
+ * 字段或命名类型的底层类型产生的关系, label为字段名, 命名类型的label为空
+ */
+func (this *analysisTool) visitFieldRelations(source *structMeta, t ast.Expr, label string) {
+
+	shape := this.fieldShape(t)
+
+	targets := []typeMeta{}
+	position := this.currentFset.Position(t.Pos())
+
+	if shape.funcType != nil {
+		// func类型关联参数和返回值中的类型
+		fields := []*ast.Field{}
+		if shape.funcType.Params != nil {
+			fields = append(fields, shape.funcType.Params.List...)
+		}
+		if shape.funcType.Results != nil {
+			fields = append(fields, shape.funcType.Results.List...)
+		}
+		for _, field := range fields {
+			target, _ := this.analysisTypeForDependencyRelation(field.Type)
+			if target != nil && !typeMetaSliceContains(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+	} else {
+		target, _ := this.analysisTypeForDependencyRelation(t)
+		if target != nil {
+			targets = append(targets, target)
+		}
+	}
+
+	for _, target := range targets {
+
+		if label == "" && target == typeMeta(source) {
+			continue
+		}
+
+		kind := shape.kind
+		// interface的值只是引用, 不拥有实现的对象
+		if _, ok := target.(*interfaceMeta); ok && kind == relationComposition {
+			kind = relationAggregation
+		}
+
+		d := DependencyRelation{
+			source:       source,
+			target:       target,
+			kind:         kind,
+			multiplicity: shape.multiplicity,
+			qualifier:    shape.qualifier,
+			label:        label,
+			stereotype:   shape.stereotype,
+			position:     position,
+		}
+
+		this.dependencyRelations = append(this.dependencyRelations, &d)
+	}
+
+	// 泛型实例化的类型实参, 例如 items []Box[User] 同时关联User
+	for _, typeArg := range collectTypeArgs(t) {
+		argType1, _ := this.analysisTypeForDependencyRelation(typeArg)
+		if argType1 == nil || typeMetaSliceContains(targets, argType1) {
+			continue
+		}
+		targets = append(targets, argType1)
+
+		d := DependencyRelation{
+			source:   source,
+			target:   argType1,
+			kind:     relationAssociation,
+			label:    label,
+			position: position,
+		}
+
+		this.dependencyRelations = append(this.dependencyRelations, &d)
+	}
+}
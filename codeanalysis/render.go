@@ -0,0 +1,114 @@
+package codeanalysis
+
+import (
+	"strings"
+)
+
+// 输出格式
+const (
+	FormatPlantUML = "plantuml"
+	FormatMermaid  = "mermaid"
+	FormatDot      = "dot"
+	FormatSVG      = "svg"
+)
+
+var formats = []string{FormatPlantUML, FormatMermaid, FormatDot, FormatSVG}
+
+// 把分析结果中的类型和关系转换为某种格式的类图
+type renderer interface {
+	render() string
+}
+
+func (this *analysisTool) newRenderer() renderer {
+	switch this.config.Format {
+	case FormatMermaid:
+		return &mermaidRenderer{tool: this}
+	case FormatDot:
+		return &dotRenderer{tool: this}
+	case FormatSVG:
+		return &svgRenderer{tool: this}
+	}
+	return newPlantUMLRenderer(this)
+}
+
+/**
+ * 图中显示的关系, 两端的类型都要显示, 边界类型之间的关系不显示
+ */
+func (this *analysisTool) shownRelations() []*DependencyRelation {
+	result := []*DependencyRelation{}
+	for _, d := range this.dependencyRelations {
+		if this.showRelation(d.source, d.target) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// 图中的一组节点, 同一个包中的类型放在一组, 生成的类型和测试中的类型单独一组
+type nodeGroup struct {
+	PackagePath string
+	// 单独分组的类型, generated或test, 其他类型为空
+	Layer string
+	Nodes []typeMeta
+}
+
+// 单独分组的背景色和边框色
+var layerColors = map[string][2]string{
+	generatedNodeName: {"#f0f0f0", "#999999"},
+	layerTest:         {"#eaf5ea", "#5b9a5b"},
+}
+
+/**
+ * 类型所在的单独分组, 生成的类型为generated, 测试文件中的类型为test
+ */
+func (this *analysisTool) layerOf(node typeMeta) string {
+	if this.generatedStyle(node) {
+		return generatedNodeName
+	}
+	if this.isTestType(node) {
+		return layerTest
+	}
+	return ""
+}
+
+/**
+ * 节点分组, 按照第一次出现的顺序
+ */
+func (this *analysisTool) groupNodes(nodes []typeMeta) []*nodeGroup {
+
+	groups := []*nodeGroup{}
+
+	for _, node := range nodes {
+
+		packagePath := typeMetaPackagePath(node)
+		layer := this.layerOf(node)
+
+		var group *nodeGroup
+		for _, existGroup := range groups {
+			if existGroup.PackagePath == packagePath && existGroup.Layer == layer {
+				group = existGroup
+				break
+			}
+		}
+		if group == nil {
+			group = &nodeGroup{PackagePath: packagePath, Layer: layer}
+			groups = append(groups, group)
+		}
+
+		group.Nodes = append(group.Nodes, node)
+	}
+
+	return groups
+}
+
+/**
+ * Mermaid和DOT中的标识符只使用字母, 数字和下划线, 例如 github.com/a/b 转换为 github_com_a_b
+ */
+func safeId(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+}
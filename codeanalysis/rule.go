@@ -0,0 +1,253 @@
+package codeanalysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// 架构规则, 包路径可以使用相对于代码目录的路径, 也可以使用完整的包路径
+// * 匹配路径中的一段, ** 匹配任意多段, 例如 internal/domain/** 匹配 internal/domain 和它的所有子包
+type Rule struct {
+	// 规则名, 显示在违反规则的信息中
+	Name string `json:"name"`
+	// 规则适用的包
+	From string `json:"from"`
+	// 不能import的包
+	Deny []string `json:"deny"`
+	// 只能通过interface使用的包, from中的类型和包级别的函数都不能使用其中的struct和其他命名类型
+	InterfacesOnly []string `json:"interfacesOnly"`
+}
+
+// 包级别的函数中使用的类型, 只在检查interfacesOnly时使用
+type funcUsesMeta struct {
+	PackagePath string
+	Name        string
+	Uses        []*typeUse
+}
+
+// 规则文件, 例如 {"rules": [{"name": "domain不能依赖infra", "from": "internal/domain/**", "deny": ["internal/infra/**"]}]}
+type ruleFile struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// 违反架构规则的一个依赖
+type RuleViolation struct {
+	Rule *Rule
+	// import语句或者使用类型的位置
+	Position token.Position
+	Message  string
+}
+
+/**
+ * 违反规则的信息, 例如 /appdev/a/internal/domain/user.go:5: domain不能依赖infra: ...
+ */
+func (this *RuleViolation) String() string {
+	return positionToString(this.Position) + ": " + this.Rule.Name + ": " + this.Message
+}
+
+/**
+ * 读取JSON格式的规则文件
+ */
+func LoadRules(file string) ([]*Rule, error) {
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := &ruleFile{}
+	if err := json.Unmarshal(content, rules); err != nil {
+		return nil, fmt.Errorf("规则文件%s格式错误, %s", file, err)
+	}
+
+	if len(rules.Rules) == 0 {
+		return nil, fmt.Errorf("规则文件%s中没有规则", file)
+	}
+
+	for index, rule := range rules.Rules {
+
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("规则%d", index+1)
+		}
+
+		if rule.From == "" {
+			return nil, fmt.Errorf("%s没有设置from", rule.Name)
+		}
+
+		if len(rule.Deny) == 0 && len(rule.InterfacesOnly) == 0 {
+			return nil, fmt.Errorf("%s没有设置deny或者interfacesOnly", rule.Name)
+		}
+
+		for _, pattern := range append(append([]string{rule.From}, rule.Deny...), rule.InterfacesOnly...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("%s中的包路径%s格式错误", rule.Name, pattern)
+			}
+		}
+	}
+
+	return rules.Rules, nil
+}
+
+/**
+ * 包路径是否匹配规则中的包路径, * 匹配一段, ** 匹配任意多段
+ */
+func matchPackagePattern(pattern string, packagePath string) bool {
+	return matchPathSegments(strings.Split(pattern, "/"), strings.Split(packagePath, "/"))
+}
+
+func matchPathSegments(patterns []string, segments []string) bool {
+
+	if len(patterns) == 0 {
+		return len(segments) == 0
+	}
+
+	if patterns[0] == "**" {
+		for index := 0; index <= len(segments); index++ {
+			if matchPathSegments(patterns[1:], segments[index:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	matched, _ := path.Match(patterns[0], segments[0])
+
+	return matched && matchPathSegments(patterns[1:], segments[1:])
+}
+
+/**
+ * 包路径是否匹配其中一个规则中的包路径, 代码目录中的包同时使用相对于代码目录的路径匹配
+ */
+func (this *analysisTool) matchPackage(patterns []string, packagePath string) bool {
+
+	rootPackagePath := this.dirToPackagePath(path.Clean(this.config.CodeDir))
+	relativePath := ""
+	if strings.HasPrefix(packagePath, rootPackagePath+"/") {
+		relativePath = strings.TrimPrefix(packagePath, rootPackagePath+"/")
+	}
+
+	for _, pattern := range patterns {
+		if matchPackagePattern(pattern, packagePath) || relativePath != "" && matchPackagePattern(pattern, relativePath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/**
+ * 包级别的函数的参数, 返回值和函数体中使用的类型, 测试函数不检查
+ */
+func (this *analysisTool) visitFuncUses(funcDecl *ast.FuncDecl) {
+
+	if len(this.config.Rules) == 0 || funcDecl.Recv != nil || isTestFile(this.currentFile) {
+		return
+	}
+
+	funcUses := &funcUsesMeta{
+		PackagePath: this.currentPackagePath,
+		Name:        funcDecl.Name.Name,
+	}
+
+	if this.hasRelationSource(RelationSignatures) {
+		this.visitSignatureUses(&funcUses.Uses, funcDecl.Name.Name, funcDecl.Type)
+	}
+
+	if this.hasRelationSource(RelationBodies) {
+		this.visitBodyUses(&funcUses.Uses, funcDecl.Name.Name, funcDecl.Body)
+	}
+
+	if len(funcUses.Uses) > 0 {
+		this.funcUses = append(this.funcUses, funcUses)
+	}
+}
+
+/**
+ * 使用包的import关系检查deny, 使用类型之间的关系和包级别的函数中使用的类型检查interfacesOnly
+ */
+func (this *analysisTool) checkRules() {
+
+	for _, rule := range this.config.Rules {
+
+		for _, edge := range this.packageEdges {
+			if !this.matchPackage([]string{rule.From}, edge.source.Name) || !this.matchPackage(rule.Deny, edge.target.Name) {
+				continue
+			}
+			for _, position := range edge.positions {
+				this.addRuleViolation(rule, position, fmt.Sprintf("%s import了%s", edge.source.Name, edge.target.Name))
+			}
+		}
+
+		if len(rule.InterfacesOnly) == 0 {
+			continue
+		}
+
+		for _, d := range this.dependencyRelations {
+
+			sourcePackagePath := typeMetaPackagePath(d.source)
+			targetPackagePath := typeMetaPackagePath(d.target)
+
+			// 测试代码可以直接使用具体类型
+			if sourcePackagePath == targetPackagePath || this.isTestType(d.source) || !this.matchPackage([]string{rule.From}, sourcePackagePath) ||
+				!this.matchPackage(rule.InterfacesOnly, targetPackagePath) {
+				continue
+			}
+
+			if _, ok := d.target.(*interfaceMeta); ok {
+				continue
+			}
+
+			this.addRuleViolation(rule, d.position, fmt.Sprintf("%s.%s %s 使用了%s.%s, 只能通过interface使用",
+				sourcePackagePath, typeMetaName(d.source), relationReason(d), targetPackagePath, typeMetaName(d.target)))
+		}
+
+		for _, funcUses := range this.funcUses {
+
+			if !this.matchPackage([]string{rule.From}, funcUses.PackagePath) {
+				continue
+			}
+
+			for _, use := range funcUses.Uses {
+
+				targetPackagePath := typeMetaPackagePath(use.target)
+				if targetPackagePath == funcUses.PackagePath || !this.matchPackage(rule.InterfacesOnly, targetPackagePath) {
+					continue
+				}
+
+				if _, ok := use.target.(*interfaceMeta); ok {
+					continue
+				}
+
+				this.addRuleViolation(rule, use.position, fmt.Sprintf("%s 函数 %s 使用了%s.%s, 只能通过interface使用",
+					funcUses.PackagePath, funcUses.Name, targetPackagePath, typeMetaName(use.target)))
+			}
+		}
+	}
+
+	if len(this.ruleViolations) > 0 {
+		log.Warnf("发现%d个违反架构规则的依赖", len(this.ruleViolations))
+	}
+}
+
+func (this *analysisTool) addRuleViolation(rule *Rule, position token.Position, message string) {
+	this.ruleViolations = append(this.ruleViolations, &RuleViolation{
+		Rule:     rule,
+		Position: position,
+		Message:  message,
+	})
+}
+
+func (this *analysisTool) RuleViolations() []*RuleViolation {
+	return this.ruleViolations
+}
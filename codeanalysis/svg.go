@@ -0,0 +1,871 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// 不依赖Java和Graphviz的SVG类图, 使用分层布局
+// 被依赖的类型在上层, 依赖它的类型在下层, 同一层中按照相邻层的重心排序以减少交叉
+type svgRenderer struct {
+	tool *analysisTool
+}
+
+const (
+	svgCharWidth   = 7.2
+	svgLineHeight  = 16.0
+	svgPadding     = 8.0
+	svgMargin      = 20.0
+	svgNodeGap     = 30.0
+	svgPackageGap  = 50.0
+	svgLayerGap    = 80.0
+	svgFramePad    = 10.0
+	svgFrameLabel  = 18.0
+	svgMinWidth    = 80.0
+	svgSweepRounds = 4
+)
+
+// SVG图中的节点
+type svgNode struct {
+	meta        typeMeta
+	packagePath string
+	// 节点上方居中的行, 类型的种类和类型名
+	header []string
+	// 成员行, 每组成员之间画分隔线
+	lines []string
+	// 每组成员开始的行号
+	groupStarts []int
+	// 在输入中的顺序, 同一层中作为初始顺序
+	index int
+	layer int
+	// 在所在层中的位置, 排序使用
+	position float64
+	x, y     float64
+	width    float64
+	height   float64
+}
+
+// SVG图中的边, 从source指向target
+type svgEdge struct {
+	source *svgNode
+	target *svgNode
+	dashed bool
+	// 在循环依赖中, 使用红色
+	cycle bool
+	// source端和target端的标记, 例如 diamond, triangle, vee
+	startMarker string
+	endMarker   string
+	label       string
+	// target端的多重性
+	headLabel string
+	// source端的限定符
+	tailLabel string
+}
+
+// 同一层中同一个包里相邻的节点, 画一个包的边框
+type svgPackageRun struct {
+	packagePath string
+	nodes       []*svgNode
+	x, y        float64
+	width       float64
+	height      float64
+}
+
+func (this *svgRenderer) render() string {
+
+	nodes, edges := this.layout()
+	runs := this.packageRuns(nodes)
+
+	// 画布包含所有节点和包的边框, 包的import关系图中没有包的边框
+	width := 0.0
+	height := 0.0
+	for _, node := range nodes {
+		width = svgMax(width, node.x+node.width+svgMargin)
+		height = svgMax(height, node.y+node.height+svgMargin)
+	}
+	for _, run := range runs {
+		width = svgMax(width, run.x+run.width+svgMargin)
+		height = svgMax(height, run.y+run.height+svgMargin)
+	}
+
+	result := fmt.Sprintf("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%s\" height=\"%s\" viewBox=\"0 0 %s %s\">\n",
+		svgNumber(width), svgNumber(height), svgNumber(width), svgNumber(height))
+
+	result += "<style>\n" +
+		"  text { font-family: monospace; font-size: 12px; fill: #000000; }\n" +
+		"  .package { fill: #f8f8f8; stroke: #999999; stroke-dasharray: 4 2; }\n" +
+		"  .package-name { fill: #666666; font-size: 11px; }\n" +
+		"  .node { fill: #fefece; stroke: #a80036; }\n" +
+		"  .excluded { fill: #eeeeee; stroke: #999999; }\n" +
+		"  .generated { fill: #f0f0f0; stroke: #999999; }\n" +
+		"  .test { fill: #eaf5ea; stroke: #5b9a5b; }\n" +
+		"  .edge { fill: none; stroke: #a80036; }\n" +
+		"  .dashed { stroke-dasharray: 6 4; }\n" +
+		"  .cycle { stroke: #ff0000; stroke-width: 2; }\n" +
+		"  .label { font-size: 11px; }\n" +
+		"</style>\n"
+
+	result += "<defs>\n" +
+		"  <marker id=\"diamond\" markerWidth=\"12\" markerHeight=\"10\" refX=\"0\" refY=\"5\" orient=\"auto\" markerUnits=\"userSpaceOnUse\">" +
+		"<path d=\"M0,5 L6,0 L12,5 L6,10 z\" fill=\"#a80036\" stroke=\"#a80036\"/></marker>\n" +
+		"  <marker id=\"odiamond\" markerWidth=\"12\" markerHeight=\"10\" refX=\"0\" refY=\"5\" orient=\"auto\" markerUnits=\"userSpaceOnUse\">" +
+		"<path d=\"M0,5 L6,0 L12,5 L6,10 z\" fill=\"#ffffff\" stroke=\"#a80036\"/></marker>\n" +
+		"  <marker id=\"triangle\" markerWidth=\"12\" markerHeight=\"12\" refX=\"12\" refY=\"6\" orient=\"auto\" markerUnits=\"userSpaceOnUse\">" +
+		"<path d=\"M0,0 L12,6 L0,12 z\" fill=\"#ffffff\" stroke=\"#a80036\"/></marker>\n" +
+		"  <marker id=\"vee\" markerWidth=\"10\" markerHeight=\"10\" refX=\"10\" refY=\"5\" orient=\"auto\" markerUnits=\"userSpaceOnUse\">" +
+		"<path d=\"M0,0 L10,5 L0,10\" fill=\"none\" stroke=\"#a80036\"/></marker>\n" +
+		"</defs>\n"
+
+	for _, run := range runs {
+		result += this.packageToSVG(run)
+	}
+
+	for _, node := range nodes {
+		result += this.nodeToSVG(node)
+	}
+
+	for _, edge := range edges {
+		result += this.edgeToSVG(edge)
+	}
+
+	return result + "</svg>\n"
+}
+
+/**
+ * 计算节点的大小和位置
+ */
+func (this *svgRenderer) layout() ([]*svgNode, []*svgEdge) {
+
+	nodes, edges := []*svgNode{}, []*svgEdge{}
+	if this.tool.config.View == ViewPackages {
+		nodes, edges = this.packageGraph()
+	} else {
+		nodes, edges = this.graph()
+	}
+
+	this.assignLayers(nodes, edges)
+	layers := this.orderLayers(nodes, edges)
+	this.placeNodes(layers)
+
+	return nodes, edges
+}
+
+/**
+ * 把分析结果转换为节点和边, 和其他格式显示相同的类型和关系
+ */
+func (this *svgRenderer) graph() ([]*svgNode, []*svgEdge) {
+
+	metas := []typeMeta{}
+
+	for _, structMeta1 := range this.tool.structMetas {
+		if this.tool.showType(structMeta1) {
+			metas = append(metas, structMeta1)
+		}
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if this.tool.showType(interfaceMeta1) {
+			metas = append(metas, interfaceMeta1)
+		}
+	}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		metas = append(metas, typeAliasMeta1)
+	}
+
+	nodes := []*svgNode{}
+	nodeMap := map[typeMeta]*svgNode{}
+
+	for index, meta := range metas {
+		node := this.createNode(meta)
+		node.index = index
+		nodes = append(nodes, node)
+		nodeMap[meta] = node
+	}
+
+	// interfaceImpl每次查找都是新的对象, 按照包路径和名称找到对应的struct
+	findNode := func(meta typeMeta) *svgNode {
+		if interfaceImpl1, ok := meta.(*interfaceImpl); ok {
+			meta = interfaceImpl1.structMeta
+		}
+		return nodeMap[meta]
+	}
+
+	edges := []*svgEdge{}
+
+	for _, typeAliasMeta1 := range this.tool.shownTypeAliases() {
+		if target := this.tool.typeAliasTarget(typeAliasMeta1); target != nil && findNode(target) != nil {
+			edges = append(edges, &svgEdge{
+				source:    findNode(typeAliasMeta1),
+				target:    findNode(target),
+				dashed:    true,
+				endMarker: "vee",
+				label:     "alias",
+			})
+		}
+	}
+
+	for _, d := range this.tool.shownRelations() {
+		source := findNode(d.source)
+		target := findNode(d.target)
+		if source == nil || target == nil {
+			continue
+		}
+		edges = append(edges, this.createEdge(d, source, target))
+	}
+
+	for _, interfaceMeta1 := range this.tool.interfaceMetas {
+		if !this.tool.showType(interfaceMeta1) {
+			continue
+		}
+		for _, interfaceImpl1 := range this.tool.findInterfaceImpls(interfaceMeta1) {
+			source := findNode(interfaceImpl1)
+			if source == nil || !this.tool.showRelation(interfaceImpl1, interfaceMeta1) {
+				continue
+			}
+			edge := &svgEdge{
+				source:    source,
+				target:    findNode(interfaceMeta1),
+				dashed:    true,
+				endMarker: "triangle",
+			}
+			if interfaceImpl1.PointerOnly {
+				edge.label = "«pointer»"
+			}
+			edges = append(edges, edge)
+		}
+	}
+
+	return nodes, edges
+}
+
+/**
+ * 包的import关系图, 每个包是一个节点, 被import的包在上层, 边上显示import的文件数量
+ */
+func (this *svgRenderer) packageGraph() ([]*svgNode, []*svgEdge) {
+
+	nodes := []*svgNode{}
+	nodeMap := map[*packageNode]*svgNode{}
+
+	for index, packageNode1 := range this.tool.shownPackageNodes() {
+
+		node := &svgNode{index: index}
+		if packageNode1.Kind != packageInternal {
+			node.header = append(node.header, "«"+packageNode1.Kind+"»")
+		}
+		node.header = append(node.header, packageNode1.Name)
+
+		node.width = svgMax(svgMinWidth, svgTextWidth(packageNode1.Name)+2*svgPadding)
+		node.height = float64(len(node.header))*svgLineHeight + 2*svgPadding
+
+		nodes = append(nodes, node)
+		nodeMap[packageNode1] = node
+	}
+
+	edges := []*svgEdge{}
+	for _, packageEdge1 := range this.tool.shownPackageEdges() {
+		edges = append(edges, &svgEdge{
+			source:    nodeMap[packageEdge1.source],
+			target:    nodeMap[packageEdge1.target],
+			dashed:    true,
+			endMarker: "vee",
+			label:     fmt.Sprintf("%d", packageEdge1.Weight),
+			cycle:     this.tool.highlightCycle(packageEdge1.cycle),
+		})
+	}
+
+	return nodes, edges
+}
+
+func (this *svgRenderer) createNode(meta typeMeta) *svgNode {
+
+	node := &svgNode{
+		meta:        meta,
+		packagePath: typeMetaPackagePath(meta),
+	}
+
+	// 生成的类型和测试中的类型画在单独的包框中
+	if layer := this.tool.layerOf(meta); layer != "" {
+		node.packagePath += " «" + layer + "»"
+	}
+
+	name := typeMetaName(meta)
+	annotation := ""
+
+	switch typeMeta1 := meta.(type) {
+	case *structMeta:
+		name += typeParamsToUML(typeMeta1.TypeParams)
+		switch {
+		case len(typeMeta1.EnumValues) > 0:
+			annotation = "enumeration"
+		case typeMeta1.UnderlyingType != "":
+			annotation = typeMeta1.UnderlyingType
+		}
+	case *interfaceMeta:
+		name += typeParamsToUML(typeMeta1.TypeParams)
+		annotation = "interface"
+		if len(typeMeta1.TypeUnion) > 0 {
+			annotation = strings.Join(typeMeta1.TypeUnion, "; ")
+		}
+	case *typeAliasMeta:
+		annotation = "alias"
+		if this.tool.typeAliasTarget(typeMeta1) == nil {
+			annotation = "alias " + typeMeta1.targetTypeName
+		}
+	}
+
+	if layer := this.tool.layerOf(meta); layer != "" {
+		annotation = strings.TrimSuffix(layer+", "+annotation, ", ")
+	}
+
+	if platform := this.tool.platformOf(meta); platform != "" {
+		annotation = strings.TrimPrefix(annotation+", "+platform, ", ")
+	}
+
+	if this.tool.isStub(meta) {
+		annotation = "stub"
+	}
+
+	if annotation != "" {
+		node.header = append(node.header, "«"+annotation+"»")
+	}
+	node.header = append(node.header, name)
+
+	if _, ok := meta.(*typeAliasMeta); !ok {
+		groups, hidden := this.tool.classMembers(meta)
+		for _, group := range groups {
+			node.groupStarts = append(node.groupStarts, len(node.lines))
+			if group.Title != "" {
+				node.lines = append(node.lines, "«"+group.Title+"»")
+			}
+			for _, member := range group.Members {
+				node.lines = append(node.lines, this.memberToSVG(member))
+			}
+		}
+		if hidden > 0 {
+			node.lines = append(node.lines, fmt.Sprintf("... %d more", hidden))
+		}
+	}
+
+	width := svgMinWidth
+	for _, line := range append(append([]string{}, node.header...), node.lines...) {
+		width = svgMax(width, svgTextWidth(line)+2*svgPadding)
+	}
+
+	node.width = width
+	node.height = float64(len(node.header))*svgLineHeight + 2*svgPadding
+	if len(node.lines) > 0 {
+		// 每组成员之间的分隔线上下各留出一半的间距
+		node.height += float64(len(node.lines))*svgLineHeight + float64(len(node.groupStarts)+1)*svgPadding
+	}
+
+	return node
+}
+
+func (this *svgRenderer) memberToSVG(member *memberMeta) string {
+
+	if !member.Method {
+		return member.Visibility + strings.TrimSpace(member.Name+" "+member.Type)
+	}
+
+	line := member.Visibility + member.Name + "(" + member.Params + ")"
+	if member.Results != "" {
+		line += " " + member.Results
+	}
+	if member.PointerReceiver {
+		line += " «pointer»"
+	}
+
+	return line
+}
+
+func (this *svgRenderer) createEdge(d *DependencyRelation, source *svgNode, target *svgNode) *svgEdge {
+
+	edge := &svgEdge{
+		source:    source,
+		target:    target,
+		label:     d.label,
+		headLabel: d.multiplicity,
+		cycle:     this.tool.highlightCycle(d.cycle),
+	}
+
+	if d.stereotype != "" {
+		edge.label = strings.TrimSpace(edge.label + " «" + d.stereotype + "»")
+	}
+	if d.qualifier != "" {
+		edge.tailLabel = "[" + d.qualifier + "]"
+	}
+
+	switch d.kind {
+	case relationComposition:
+		edge.startMarker = "diamond"
+	case relationAggregation:
+		edge.startMarker = "odiamond"
+	case relationAssociation:
+		edge.endMarker = "vee"
+	case relationDependency:
+		edge.dashed = true
+		edge.endMarker = "vee"
+	case relationExtends:
+		edge.endMarker = "triangle"
+	case relationEmbeds:
+		edge.dashed = true
+		edge.startMarker = "diamond"
+		if d.pointer {
+			edge.startMarker = "odiamond"
+		}
+	}
+
+	return edge
+}
+
+/**
+ * 分层, 没有依赖其他节点的在第0层, 其他节点在所有依赖的节点的下一层
+ * 循环依赖中回到正在访问的节点的边不参与分层
+ */
+func (this *svgRenderer) assignLayers(nodes []*svgNode, edges []*svgEdge) {
+
+	targets := map[*svgNode][]*svgNode{}
+	for _, edge := range edges {
+		if edge.source != edge.target {
+			targets[edge.source] = append(targets[edge.source], edge.target)
+		}
+	}
+
+	visiting := map[*svgNode]bool{}
+	done := map[*svgNode]bool{}
+
+	var visit func(node *svgNode)
+	visit = func(node *svgNode) {
+
+		if done[node] || visiting[node] {
+			return
+		}
+		visiting[node] = true
+
+		layer := 0
+		for _, target := range targets[node] {
+			if visiting[target] {
+				continue
+			}
+			visit(target)
+			if target.layer+1 > layer {
+				layer = target.layer + 1
+			}
+		}
+
+		node.layer = layer
+		visiting[node] = false
+		done[node] = true
+	}
+
+	for _, node := range nodes {
+		visit(node)
+	}
+}
+
+/**
+ * 同一层中的节点排序, 按照包分组, 包内按照相邻层中连接的节点的平均位置排序, 上下交替扫描几次
+ */
+func (this *svgRenderer) orderLayers(nodes []*svgNode, edges []*svgEdge) [][]*svgNode {
+
+	layers := [][]*svgNode{}
+	packageIndex := map[string]int{}
+
+	for _, node := range nodes {
+		for len(layers) <= node.layer {
+			layers = append(layers, []*svgNode{})
+		}
+		layers[node.layer] = append(layers[node.layer], node)
+		if _, ok := packageIndex[node.packagePath]; !ok {
+			packageIndex[node.packagePath] = len(packageIndex)
+		}
+	}
+
+	neighbors := map[*svgNode][]*svgNode{}
+	for _, edge := range edges {
+		if edge.source != edge.target {
+			neighbors[edge.source] = append(neighbors[edge.source], edge.target)
+			neighbors[edge.target] = append(neighbors[edge.target], edge.source)
+		}
+	}
+
+	sortLayer := func(layer []*svgNode, barycenter map[*svgNode]float64) {
+		sort.SliceStable(layer, func(i, j int) bool {
+			pi := packageIndex[layer[i].packagePath]
+			pj := packageIndex[layer[j].packagePath]
+			if pi != pj {
+				return pi < pj
+			}
+			return barycenter[layer[i]] < barycenter[layer[j]]
+		})
+		for position, node := range layer {
+			node.position = float64(position)
+		}
+	}
+
+	for _, layer := range layers {
+		barycenter := map[*svgNode]float64{}
+		for _, node := range layer {
+			barycenter[node] = float64(node.index)
+		}
+		sortLayer(layer, barycenter)
+	}
+
+	// 相邻层中连接的节点的平均位置, 没有连接时保持原来的位置
+	sweep := func(layer []*svgNode, adjacent int) {
+		barycenter := map[*svgNode]float64{}
+		for _, node := range layer {
+			sum := 0.0
+			count := 0
+			for _, neighbor := range neighbors[node] {
+				if neighbor.layer == adjacent {
+					sum += neighbor.position
+					count++
+				}
+			}
+			if count > 0 {
+				barycenter[node] = sum / float64(count)
+			} else {
+				barycenter[node] = node.position
+			}
+		}
+		sortLayer(layer, barycenter)
+	}
+
+	for round := 0; round < svgSweepRounds; round++ {
+		for index := 1; index < len(layers); index++ {
+			sweep(layers[index], index-1)
+		}
+		for index := len(layers) - 2; index >= 0; index-- {
+			sweep(layers[index], index+1)
+		}
+	}
+
+	return layers
+}
+
+/**
+ * 计算节点坐标, 每层水平居中, 同一个包中相邻的节点间距较小
+ */
+func (this *svgRenderer) placeNodes(layers [][]*svgNode) {
+
+	layerWidths := []float64{}
+	maxWidth := 0.0
+
+	for _, layer := range layers {
+		width := 0.0
+		for _, run := range svgSplitRuns(layer) {
+			if width > 0 {
+				width += svgPackageGap
+			}
+			width += svgRunWidth(run)
+		}
+		layerWidths = append(layerWidths, width)
+		maxWidth = svgMax(maxWidth, width)
+	}
+
+	y := svgMargin
+
+	for index, layer := range layers {
+
+		x := svgMargin + (maxWidth-layerWidths[index])/2
+		layerHeight := 0.0
+
+		for _, run := range svgSplitRuns(layer) {
+
+			runWidth := svgRunWidth(run)
+			nodeX := x + (runWidth-svgNodesWidth(run))/2
+
+			for _, node := range run {
+				node.x = nodeX
+				node.y = y + svgFrameLabel + svgFramePad
+				nodeX += node.width + svgNodeGap
+				layerHeight = svgMax(layerHeight, node.height)
+			}
+
+			x += runWidth + svgPackageGap
+		}
+
+		y += svgFrameLabel + layerHeight + 2*svgFramePad + svgLayerGap
+	}
+}
+
+/**
+ * 同一层中属于同一个包的相邻节点, 画成一个包的边框
+ */
+func (this *svgRenderer) packageRuns(nodes []*svgNode) []*svgPackageRun {
+
+	layers := map[int][]*svgNode{}
+	maxLayer := -1
+	for _, node := range nodes {
+		layers[node.layer] = append(layers[node.layer], node)
+		if node.layer > maxLayer {
+			maxLayer = node.layer
+		}
+	}
+
+	runs := []*svgPackageRun{}
+
+	for index := 0; index <= maxLayer; index++ {
+
+		layer := layers[index]
+		sort.SliceStable(layer, func(i, j int) bool {
+			return layer[i].x < layer[j].x
+		})
+
+		for _, nodesInRun := range svgSplitRuns(layer) {
+
+			// 包的import关系图中的节点不画包的边框
+			if nodesInRun[0].packagePath == "" {
+				continue
+			}
+
+			left := nodesInRun[0].x
+			right := nodesInRun[len(nodesInRun)-1].x + nodesInRun[len(nodesInRun)-1].width
+			height := 0.0
+			for _, node := range nodesInRun {
+				height = svgMax(height, node.height)
+			}
+
+			width := svgMax(right-left, svgTextWidth(nodesInRun[0].packagePath))
+			center := (left + right) / 2
+
+			runs = append(runs, &svgPackageRun{
+				packagePath: nodesInRun[0].packagePath,
+				nodes:       nodesInRun,
+				x:           center - width/2 - svgFramePad,
+				y:           nodesInRun[0].y - svgFrameLabel - svgFramePad,
+				width:       width + 2*svgFramePad,
+				height:      height + svgFrameLabel + 2*svgFramePad,
+			})
+		}
+	}
+
+	return runs
+}
+
+func (this *svgRenderer) packageToSVG(run *svgPackageRun) string {
+	return fmt.Sprintf("<rect class=\"package\" x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" rx=\"4\"/>\n"+
+		"<text class=\"package-name\" x=\"%s\" y=\"%s\">%s</text>\n",
+		svgNumber(run.x), svgNumber(run.y), svgNumber(run.width), svgNumber(run.height),
+		svgNumber(run.x+svgFramePad), svgNumber(run.y+svgFrameLabel-4), htmlEscape(run.packagePath))
+}
+
+func (this *svgRenderer) nodeToSVG(node *svgNode) string {
+
+	class := "node"
+	if node.meta != nil && this.tool.isExcluded(node.meta) {
+		class += " excluded"
+	} else if node.meta != nil && this.tool.layerOf(node.meta) != "" {
+		class += " " + this.tool.layerOf(node.meta)
+	}
+
+	result := fmt.Sprintf("<g>\n<rect class=\"%s\" x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\"/>\n", class,
+		svgNumber(node.x), svgNumber(node.y), svgNumber(node.width), svgNumber(node.height))
+
+	y := node.y + svgPadding
+	center := node.x + node.width/2
+
+	for index, line := range node.header {
+		y += svgLineHeight
+		weight := ""
+		if index == len(node.header)-1 {
+			weight = " font-weight=\"bold\""
+		}
+		result += fmt.Sprintf("<text x=\"%s\" y=\"%s\" text-anchor=\"middle\"%s>%s</text>\n",
+			svgNumber(center), svgNumber(y-4), weight, htmlEscape(line))
+	}
+
+	y += svgPadding
+
+	for index, line := range node.lines {
+		if sliceContainsInt(node.groupStarts, index) {
+			if index > 0 {
+				y += svgPadding / 2
+			}
+			result += fmt.Sprintf("<line class=\"edge\" x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\"/>\n",
+				svgNumber(node.x), svgNumber(y), svgNumber(node.x+node.width), svgNumber(y))
+			if index > 0 {
+				y += svgPadding / 2
+			} else {
+				y += svgPadding
+			}
+		}
+		y += svgLineHeight
+		result += fmt.Sprintf("<text x=\"%s\" y=\"%s\">%s</text>\n",
+			svgNumber(node.x+svgPadding), svgNumber(y-4), htmlEscape(line))
+	}
+
+	return result + "</g>\n"
+}
+
+/**
+ * 边为两个节点中心的连线, 截断在节点的边框上
+ */
+func (this *svgRenderer) edgeToSVG(edge *svgEdge) string {
+
+	x1, y1, x2, y2 := 0.0, 0.0, 0.0, 0.0
+
+	if edge.source == edge.target {
+		// 自己指向自己的边画在节点右侧
+		node := edge.source
+		x1 = node.x + node.width
+		y1 = node.y + node.height/3
+		x2 = x1
+		y2 = node.y + node.height*2/3
+	} else {
+		x1, y1 = svgClip(edge.source, edge.target)
+		x2, y2 = svgClip(edge.target, edge.source)
+	}
+
+	class := "edge"
+	if edge.dashed {
+		class += " dashed"
+	}
+	if edge.cycle {
+		class += " cycle"
+	}
+
+	markers := ""
+	if edge.startMarker != "" {
+		markers += " marker-start=\"url(#" + edge.startMarker + ")\""
+	}
+	if edge.endMarker != "" {
+		markers += " marker-end=\"url(#" + edge.endMarker + ")\""
+	}
+
+	result := ""
+
+	if edge.source == edge.target {
+		result = fmt.Sprintf("<path class=\"%s\" d=\"M%s,%s C%s,%s %s,%s %s,%s\"%s/>\n", class,
+			svgNumber(x1), svgNumber(y1), svgNumber(x1+40), svgNumber(y1), svgNumber(x2+40), svgNumber(y2),
+			svgNumber(x2), svgNumber(y2), markers)
+	} else {
+		result = fmt.Sprintf("<line class=\"%s\" x1=\"%s\" y1=\"%s\" x2=\"%s\" y2=\"%s\"%s/>\n", class,
+			svgNumber(x1), svgNumber(y1), svgNumber(x2), svgNumber(y2), markers)
+	}
+
+	label := func(text string, ratio float64, anchor string) string {
+		if text == "" {
+			return ""
+		}
+		x := x1 + (x2-x1)*ratio
+		y := y1 + (y2-y1)*ratio
+		if edge.source == edge.target {
+			x += 40
+		}
+		return fmt.Sprintf("<text class=\"label\" x=\"%s\" y=\"%s\" text-anchor=\"%s\">%s</text>\n",
+			svgNumber(x+4), svgNumber(y-4), anchor, htmlEscape(text))
+	}
+
+	result += label(edge.label, 0.5, "start")
+	result += label(edge.headLabel, 0.85, "start")
+	result += label(edge.tailLabel, 0.15, "start")
+
+	return result
+}
+
+/**
+ * from节点中心指向to节点中心的线和from节点边框的交点
+ */
+func svgClip(from *svgNode, to *svgNode) (float64, float64) {
+
+	cx := from.x + from.width/2
+	cy := from.y + from.height/2
+	dx := to.x + to.width/2 - cx
+	dy := to.y + to.height/2 - cy
+
+	if dx == 0 && dy == 0 {
+		return cx, cy
+	}
+
+	scale := 1e18
+	if dx != 0 {
+		scale = svgMin(scale, from.width/2/svgAbs(dx))
+	}
+	if dy != 0 {
+		scale = svgMin(scale, from.height/2/svgAbs(dy))
+	}
+
+	return cx + dx*scale, cy + dy*scale
+}
+
+/**
+ * 同一层中按包分成连续的几段
+ */
+func svgSplitRuns(layer []*svgNode) [][]*svgNode {
+	runs := [][]*svgNode{}
+	for _, node := range layer {
+		last := len(runs) - 1
+		if last >= 0 && runs[last][0].packagePath == node.packagePath {
+			runs[last] = append(runs[last], node)
+		} else {
+			runs = append(runs, []*svgNode{node})
+		}
+	}
+	return runs
+}
+
+func svgNodesWidth(run []*svgNode) float64 {
+	width := 0.0
+	for index, node := range run {
+		if index > 0 {
+			width += svgNodeGap
+		}
+		width += node.width
+	}
+	return width
+}
+
+/**
+ * 包边框内的宽度, 包名比节点宽时使用包名的宽度
+ */
+func svgRunWidth(run []*svgNode) float64 {
+	return svgMax(svgNodesWidth(run), svgTextWidth(run[0].packagePath)) + 2*svgFramePad
+}
+
+func svgTextWidth(text string) float64 {
+	return float64(utf8.RuneCountInString(text)) * svgCharWidth
+}
+
+/**
+ * 坐标保留一位小数
+ */
+func svgNumber(value float64) string {
+	result := fmt.Sprintf("%.1f", value)
+	return strings.TrimSuffix(result, ".0")
+}
+
+func svgMax(a float64, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func svgMin(a float64, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func svgAbs(a float64) float64 {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func sliceContainsInt(src []int, value int) bool {
+	for _, srcValue := range src {
+		if srcValue == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,209 @@
+package codeanalysis
+
+import (
+	"go/ast"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 测试代码的分组和构造型
+const layerTest = "test"
+
+// 和go test相同的测试函数前缀
+var testFuncPrefixes = []string{"Test", "Benchmark", "Fuzz", "Example"}
+
+// 测试文件的节点, 成员为文件中的测试函数
+type testFileMeta struct {
+	node *structMeta
+	// 每个测试函数使用的类型, 同一个类型可以被多个测试函数使用
+	uses []*typeUse
+}
+
+func isTestFile(file string) bool {
+	return strings.HasSuffix(file, "_test.go")
+}
+
+/**
+ * 和go test一样判断测试函数, 前缀后面为空或者不是小写字母, 例如 TestUser, Test_user, 不包括 Testuser
+ */
+func isTestFunc(funcDecl *ast.FuncDecl) bool {
+
+	if funcDecl.Recv != nil {
+		return false
+	}
+
+	for _, prefix := range testFuncPrefixes {
+		if !strings.HasPrefix(funcDecl.Name.Name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(funcDecl.Name.Name, prefix)
+		if rest == "" {
+			return true
+		}
+		r, _ := utf8.DecodeRuneInString(rest)
+		return !unicode.IsLower(r)
+	}
+
+	return false
+}
+
+/**
+ * 外部测试包 package xxx_test 使用单独的包路径, 例如 github.com/a/b_test
+ */
+func (this *analysisTool) initTestPackage(file *ast.File) {
+	if isTestFile(this.currentFile) && strings.HasSuffix(file.Name.Name, "_test") {
+		this.currentPackagePath += "_test"
+	}
+}
+
+/**
+ * 类型是否在测试文件中, 包括测试文件的节点
+ */
+func (this *analysisTool) isTestType(typeMeta1 typeMeta) bool {
+	return isTestFile(typeMetaFilePath(typeMeta1))
+}
+
+/**
+ * 为有测试函数的测试文件创建节点, 例如 service_test.go 的节点为 service_test, 记录每个测试函数使用的类型
+ */
+func (this *analysisTool) visitTestFuncs(file *ast.File) {
+
+	var testFile *testFileMeta
+
+	for _, decl := range file.Decls {
+
+		funcDecl, ok := decl.(*ast.FuncDecl)
+		if !ok || !isTestFunc(funcDecl) {
+			continue
+		}
+
+		if testFile == nil {
+			testFile = &testFileMeta{
+				node: &structMeta{
+					baseInfo: baseInfo{
+						FilePath:    this.currentFile,
+						PackagePath: this.currentPackagePath,
+					},
+					Name: safeId(strings.TrimSuffix(path.Base(this.currentFile), ".go")),
+				},
+			}
+			this.structMetas = append(this.structMetas, testFile.node)
+			this.testFiles = append(this.testFiles, testFile)
+		}
+
+		params, results := this.methodSignature(funcDecl.Type)
+		testFile.node.Methods = append(testFile.node.Methods, &methodMeta{
+			Name:    funcDecl.Name.Name,
+			Sign:    this.createMethodSign(funcDecl.Name.Name, funcDecl.Type),
+			Params:  params,
+			Results: results,
+		})
+
+		uses := []*typeUse{}
+		this.visitBodyUses(&uses, funcDecl.Name.Name, funcDecl.Body)
+		this.visitTestBodyUses(&uses, funcDecl.Name.Name, funcDecl.Body)
+		testFile.uses = append(testFile.uses, uses...)
+	}
+}
+
+/**
+ * 测试函数中使用的其他类型: 出现在表达式中的类型名, 例如 new(Cache), x.(*Cache), 以及构造函数 NewCache() 返回的 Cache
+ */
+func (this *analysisTool) visitTestBodyUses(uses *[]*typeUse, method string, body *ast.BlockStmt) {
+
+	if body == nil {
+		return
+	}
+
+	var visit func(node ast.Node) bool
+	visit = func(node ast.Node) bool {
+
+		switch expr := node.(type) {
+		case *ast.SelectorExpr:
+			// 只有 包名.类型名 是类型, x.Store 中的 Store 是字段或方法, 不再检查
+			if this.isTypeNameExpr(expr) {
+				*uses = this.appendUses(*uses, method, expr, this.typeRefs(expr)...)
+			} else {
+				ast.Inspect(expr.X, visit)
+			}
+			return false
+		case *ast.Ident:
+			if this.isTypeNameExpr(expr) {
+				*uses = this.appendUses(*uses, method, expr, this.typeRefs(expr)...)
+			}
+		case *ast.CallExpr:
+			if typeMeta1 := this.constructorType(expr.Fun); typeMeta1 != nil {
+				*uses = this.appendUses(*uses, method, expr.Fun, typeMeta1)
+			}
+		}
+
+		return true
+	}
+
+	ast.Inspect(body, visit)
+}
+
+/**
+ * 按照命名习惯, NewXxx 和 pkg.NewXxx 返回同一个包中的 Xxx
+ */
+func (this *analysisTool) constructorType(fun ast.Expr) typeMeta {
+
+	switch expr := fun.(type) {
+	case *ast.Ident:
+		if strings.HasPrefix(expr.Name, "New") {
+			return this.findTypeMeta(this.currentPackagePath, strings.TrimPrefix(expr.Name, "New"))
+		}
+	case *ast.SelectorExpr:
+		alias, ok := expr.X.(*ast.Ident)
+		if !ok || !strings.HasPrefix(expr.Sel.Name, "New") {
+			return nil
+		}
+		for _, importMeta := range this.currentFileImports {
+			if importMeta.Alias == alias.Name {
+				return this.findTypeMeta(importMeta.Path, strings.TrimPrefix(expr.Sel.Name, "New"))
+			}
+		}
+	}
+
+	return nil
+}
+
+/**
+ * 测试文件到使用的类型画 ..> , 标签为使用这个类型的测试函数
+ */
+func (this *analysisTool) visitTestRelations() {
+
+	for _, testFile := range this.testFiles {
+
+		targets := []typeMeta{}
+		methods := map[typeMeta][]string{}
+		positions := map[typeMeta]*typeUse{}
+
+		for _, use := range testFile.uses {
+			if use.target == testFile.node {
+				continue
+			}
+			if _, ok := methods[use.target]; !ok {
+				targets = append(targets, use.target)
+				positions[use.target] = use
+			}
+			if !sliceContains(methods[use.target], use.method) {
+				methods[use.target] = append(methods[use.target], use.method)
+			}
+		}
+
+		for _, target := range targets {
+			this.dependencyRelations = append(this.dependencyRelations, &DependencyRelation{
+				source:     testFile.node,
+				target:     target,
+				kind:       relationDependency,
+				label:      strings.Join(methods[target], ", "),
+				stereotype: layerTest,
+				position:   positions[target].position,
+				method:     positions[target].method,
+			})
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package codeanalysis
+
+import (
+	"go/ast"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+/**
+ * 确定类型别名指向的类型, 例如 type A = pkg.B 指向pkg包中的B, 需要在所有类型收集完成后执行
+ */
+func (this *analysisTool) resolveTypeAliases() {
+
+	// 按文件解析import, 同一个文件只解析一次
+	fileImports := map[string][]*importMeta{}
+
+	for _, typeAliasMeta1 := range this.typeAliasMetas {
+
+		if !typeAliasMeta1.IsAlias {
+			continue
+		}
+
+		this.currentFile = typeAliasMeta1.FilePath
+		this.currentPackagePath = typeAliasMeta1.PackagePath
+
+		imports, ok := fileImports[typeAliasMeta1.FilePath]
+		if !ok {
+			file, err := this.parseFile(typeAliasMeta1.FilePath)
+			if err != nil {
+				log.Errorf("解析文件%s失败, %s", typeAliasMeta1.FilePath, err)
+				continue
+			}
+			imports = this.parseImports(file)
+			fileImports[typeAliasMeta1.FilePath] = imports
+		}
+		this.currentFileImports = imports
+
+		typeAliasMeta1.TargetPackagePath, typeAliasMeta1.TargetName = this.resolveAliasTarget(typeAliasMeta1.targetExpr)
+	}
+
+	this.currentFileImports = nil
+
+}
+
+/**
+ * 类型别名指向的类型所在的包路径和类型名, 指针和泛型实例化指向基础类型, 内置类型和无法确定时返回空
+ */
+func (this *analysisTool) resolveAliasTarget(t ast.Expr) (packagePath string, name string) {
+
+	starExpr, ok := t.(*ast.StarExpr)
+	if ok {
+		t = starExpr.X
+	}
+
+	t = genericBaseType(t)
+
+	if resolvedPackagePath, resolvedName, ok := this.resolveTypeExpr(t); ok {
+		return resolvedPackagePath, resolvedName
+	}
+
+	switch expr := t.(type) {
+	case *ast.Ident:
+		if this.isGoBaseType(expr.Name) {
+			return "", ""
+		}
+		return this.findPackagePathByAlias("", expr.Name), expr.Name
+	case *ast.SelectorExpr:
+		return this.findPackagePathByAlias(this.selectorExprToString(expr.X), expr.Sel.Name), expr.Sel.Name
+	}
+
+	return "", ""
+}
+
+/**
+ * 如果packagePath.name是类型别名, 返回最终指向的类型, 否则原样返回
+ */
+func (this *analysisTool) followTypeAlias(packagePath string, name string) (string, string) {
+
+	// 防止别名循环引用
+	for i := 0; i < 10; i++ {
+
+		typeAliasMeta1 := this.findTypeAlias(packagePath, name)
+		if typeAliasMeta1 == nil || !typeAliasMeta1.IsAlias || typeAliasMeta1.TargetName == "" || typeAliasMeta1.TargetPackagePath == "" {
+			return packagePath, name
+		}
+
+		packagePath = typeAliasMeta1.TargetPackagePath
+		name = typeAliasMeta1.TargetName
+	}
+
+	return packagePath, name
+}
+
+func (this *typeAliasMeta) UniqueNameUML() string {
+	return packagePathToUML(this.PackagePath) + "." + this.Name
+}
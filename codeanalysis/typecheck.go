@@ -0,0 +1,293 @@
+package codeanalysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// 基于go/types的类型检查, 只从本地源码加载包, 不依赖go命令和网络
+type typeChecker struct {
+	tool *analysisTool
+	fset *token.FileSet
+	// 选择go文件时使用的构建环境
+	context build.Context
+	// 代码目录中的包共享的类型信息
+	info *types.Info
+	// 已加载的包, key为包路径, 加载失败时为nil
+	packages map[string]*types.Package
+	// 正在加载的包, 用于检测循环引用
+	loading map[string]bool
+	// 代码目录中完成类型检查的go文件, key为文件路径
+	files map[string]*ast.File
+	// 代码目录中的类型检查错误数量
+	errorCount int
+}
+
+func newTypeChecker(tool *analysisTool) *typeChecker {
+
+	// 和解析时使用相同的GOOS, GOARCH和构建标签
+	context := tool.buildContext
+	// 不处理cgo, 标准库会选择纯go的实现
+	context.CgoEnabled = false
+
+	return &typeChecker{
+		tool:    tool,
+		fset:    token.NewFileSet(),
+		context: context,
+		info: &types.Info{
+			Types: map[ast.Expr]types.TypeAndValue{},
+			Defs:  map[*ast.Ident]types.Object{},
+			Uses:  map[*ast.Ident]types.Object{},
+		},
+		packages: map[string]*types.Package{},
+		loading:  map[string]bool{},
+		files:    map[string]*ast.File{},
+	}
+}
+
+/**
+ * 对代码目录中的所有包进行类型检查
+ */
+func (this *typeChecker) checkCodeDir() {
+
+	walk := func(dir string, info os.FileInfo, err error) error {
+
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+
+		if inSomeDir(dir, this.tool.config.IgnoreDirs) {
+			return filepath.SkipDir
+		}
+
+		if _, err := this.context.ImportDir(dir, 0); err != nil {
+			if _, ok := err.(*build.NoGoError); !ok {
+				log.Warnf("类型检查时读取目录%s失败, %s", dir, err)
+			}
+			return nil
+		}
+
+		packagePath := this.tool.dirToPackagePath(dir)
+		if packagePath == "" {
+			return nil
+		}
+
+		if _, ok := this.packages[packagePath]; !ok {
+			if _, err := this.checkPackage(packagePath, dir); err != nil {
+				log.Warnf("包%s类型检查失败, 使用语法树推断类型, %s", packagePath, err)
+			}
+		}
+
+		return nil
+	}
+
+	filepath.Walk(this.tool.config.CodeDir, walk)
+
+	log.Infof("类型检查完成, 共加载%d个包, 代码目录中%d个文件", len(this.packages), len(this.files))
+
+	if this.errorCount > 0 {
+		log.Warnf("类型检查出现%d个错误, 无法确定的类型使用语法树推断", this.errorCount)
+	}
+}
+
+func (this *typeChecker) Import(packagePath string) (*types.Package, error) {
+	return this.ImportFrom(packagePath, this.tool.config.CodeDir, 0)
+}
+
+func (this *typeChecker) ImportFrom(packagePath string, fromDir string, mode types.ImportMode) (*types.Package, error) {
+
+	if packagePath == "unsafe" {
+		return types.Unsafe, nil
+	}
+
+	if pkg, ok := this.packages[packagePath]; ok {
+		if pkg == nil {
+			return nil, fmt.Errorf("包%s加载失败", packagePath)
+		}
+		return pkg, nil
+	}
+
+	dir := this.findPackageDir(fromDir, packagePath)
+	if dir == "" {
+		this.packages[packagePath] = nil
+		return nil, fmt.Errorf("找不到包%s的源码目录", packagePath)
+	}
+
+	return this.checkPackage(packagePath, dir)
+}
+
+/**
+ * 标准库在GOROOT/src中查找, 其他包使用和语法树分析相同的查找规则
+ */
+func (this *typeChecker) findPackageDir(fromDir string, packagePath string) string {
+
+	gorootSrc := path.Join(this.context.GOROOT, "src")
+
+	if strings.HasPrefix(fromDir, gorootSrc+"/") {
+		vendorDir := path.Join(gorootSrc, "vendor", packagePath)
+		if PathExists(vendorDir) {
+			return vendorDir
+		}
+	}
+
+	if !strings.Contains(strings.Split(packagePath, "/")[0], ".") {
+		stdDir := path.Join(gorootSrc, packagePath)
+		if PathExists(stdDir) {
+			return stdDir
+		}
+	}
+
+	return this.tool.findPackageDirFrom(fromDir, packagePath)
+}
+
+func (this *typeChecker) isInCodeDir(dir string) bool {
+	return inSomeDir(dir, []string{this.tool.config.CodeDir}) && !inSomeDir(dir, this.tool.config.IgnoreDirs)
+}
+
+/**
+ * 对目录中的包进行类型检查, 代码目录中的包记录类型信息, 其他包只检查声明
+ */
+func (this *typeChecker) checkPackage(packagePath string, dir string) (*types.Package, error) {
+
+	if this.loading[packagePath] {
+		return nil, fmt.Errorf("包%s存在循环引用", packagePath)
+	}
+	this.loading[packagePath] = true
+	defer delete(this.loading, packagePath)
+
+	// 先标记为失败, 检查完成后再记录结果
+	this.packages[packagePath] = nil
+
+	buildPackage, err := this.context.ImportDir(dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	inCodeDir := this.isInCodeDir(dir)
+
+	mode := parser.SkipObjectResolution
+	if inCodeDir {
+		mode = parser.ParseComments
+	}
+
+	files := []*ast.File{}
+	for _, name := range buildPackage.GoFiles {
+		file, err := parser.ParseFile(this.fset, path.Join(dir, name), nil, mode)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	var info *types.Info
+	if inCodeDir {
+		info = this.info
+	}
+
+	config := types.Config{
+		Importer:         this,
+		FakeImportC:      true,
+		IgnoreFuncBodies: !inCodeDir,
+		Error: func(err error) {
+			if inCodeDir {
+				this.errorCount++
+				log.Debugf("类型检查错误, %s", err)
+			}
+		},
+	}
+
+	pkg, _ := config.Check(packagePath, this.fset, files, info)
+
+	this.packages[packagePath] = pkg
+
+	if inCodeDir {
+		for index, name := range buildPackage.GoFiles {
+			this.files[path.Join(dir, name)] = files[index]
+		}
+	}
+
+	return pkg, nil
+}
+
+/**
+ * 类型检查模式下, 通过types.Object得到类型表达式所在的包路径和类型名称, 无法确定时ok为false
+ * 内置类型和类型参数的包路径为空, 类型别名返回指向的类型
+ */
+func (this *analysisTool) resolveTypeExpr(t ast.Expr) (packagePath string, name string, ok bool) {
+
+	if this.typeChecker == nil {
+		return
+	}
+
+	var ident *ast.Ident
+
+	switch expr := t.(type) {
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return
+	}
+
+	obj, found := this.typeChecker.info.Uses[ident]
+	if !found {
+		return
+	}
+
+	typeName, isTypeName := obj.(*types.TypeName)
+	if !isTypeName {
+		return
+	}
+
+	if named, isNamed := types.Unalias(typeName.Type()).(*types.Named); isNamed {
+		typeName = named.Obj()
+	}
+
+	if _, isTypeParam := typeName.Type().(*types.TypeParam); isTypeParam || typeName.Pkg() == nil {
+		return "", typeName.Name(), true
+	}
+
+	return typeName.Pkg().Path(), typeName.Name(), true
+}
+
+/**
+ * 类型检查模式下, 在包的作用域中查找类型
+ */
+func (this *analysisTool) lookupTypeObject(packagePath string, name string) types.Object {
+
+	if this.typeChecker == nil {
+		return nil
+	}
+
+	pkg := this.typeChecker.packages[packagePath]
+	if pkg == nil {
+		return nil
+	}
+
+	return pkg.Scope().Lookup(name)
+}
+
+/**
+ * 类型检查模式下, 判断类型是否为interface
+ */
+func (this *analysisTool) isInterfaceType(packagePath string, name string) bool {
+
+	typeObject := this.lookupTypeObject(packagePath, name)
+	if typeObject == nil {
+		return false
+	}
+
+	_, ok := typeObject.Type().Underlying().(*types.Interface)
+	return ok
+}
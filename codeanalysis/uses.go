@@ -0,0 +1,239 @@
+package codeanalysis
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+)
+
+// 关系的来源
+const (
+	// struct字段的组合, 聚合和关联
+	RelationFields = "fields"
+	// 方法参数和返回值中的类型, 画成 ..> 依赖
+	RelationSignatures = "signatures"
+	// 方法体中创建, 转换和声明的类型, 画成 ..> 依赖
+	RelationBodies = "bodies"
+)
+
+var relationSources = []string{RelationFields, RelationSignatures, RelationBodies}
+
+// 方法中使用的类型, 记录第一次使用的位置
+type typeUse struct {
+	target   typeMeta
+	method   string
+	position token.Position
+}
+
+func (this *analysisTool) hasRelationSource(source string) bool {
+	return sliceContains(this.config.RelationSources, source)
+}
+
+/**
+ * 类型表达式引用的节点, func类型包含参数和返回值中的类型, 泛型实例化包含类型实参
+ */
+func (this *analysisTool) typeRefs(t ast.Expr) []typeMeta {
+
+	refs := []typeMeta{}
+
+	add := func(typeMeta1 typeMeta) {
+		if typeMeta1 != nil && !typeMetaSliceContains(refs, typeMeta1) {
+			refs = append(refs, typeMeta1)
+		}
+	}
+
+	// 可变参数, 例如 opts ...Option
+	if ellipsis, ok := t.(*ast.Ellipsis); ok {
+		return this.typeRefs(ellipsis.Elt)
+	}
+
+	if funcType, ok := t.(*ast.FuncType); ok {
+		for _, field := range funcTypeFields(funcType) {
+			for _, ref := range this.typeRefs(field.Type) {
+				add(ref)
+			}
+		}
+		return refs
+	}
+
+	typeMeta1, _ := this.analysisTypeForDependencyRelation(t)
+	add(typeMeta1)
+
+	for _, typeArg := range collectTypeArgs(t) {
+		argTypeMeta1, _ := this.analysisTypeForDependencyRelation(typeArg)
+		add(argTypeMeta1)
+	}
+
+	return refs
+}
+
+func funcTypeFields(funcType *ast.FuncType) []*ast.Field {
+	fields := []*ast.Field{}
+	if funcType.Params != nil {
+		fields = append(fields, funcType.Params.List...)
+	}
+	if funcType.Results != nil {
+		fields = append(fields, funcType.Results.List...)
+	}
+	return fields
+}
+
+/**
+ * 方法签名中的参数和返回值依赖的类型
+ */
+func (this *analysisTool) visitSignatureUses(uses *[]*typeUse, method string, funcType *ast.FuncType) {
+	for _, field := range funcTypeFields(funcType) {
+		*uses = this.appendUses(*uses, method, field.Type, this.typeRefs(field.Type)...)
+	}
+}
+
+/**
+ * 方法体中依赖的类型: 复合字面量 Cache{}, 类型转换 Status(x), 局部变量声明 var c Cache
+ */
+func (this *analysisTool) visitBodyUses(uses *[]*typeUse, method string, body *ast.BlockStmt) {
+
+	if body == nil {
+		return
+	}
+
+	ast.Inspect(body, func(node ast.Node) bool {
+
+		switch expr := node.(type) {
+		case *ast.CompositeLit:
+			if expr.Type != nil {
+				*uses = this.appendUses(*uses, method, expr.Type, this.typeRefs(expr.Type)...)
+			}
+		case *ast.CallExpr:
+			if this.isTypeNameExpr(expr.Fun) {
+				*uses = this.appendUses(*uses, method, expr.Fun, this.typeRefs(expr.Fun)...)
+			}
+		case *ast.GenDecl:
+			if expr.Tok != token.VAR {
+				return true
+			}
+			for _, spec := range expr.Specs {
+				valueSpec, ok := spec.(*ast.ValueSpec)
+				if ok && valueSpec.Type != nil {
+					*uses = this.appendUses(*uses, method, valueSpec.Type, this.typeRefs(valueSpec.Type)...)
+				}
+			}
+		}
+
+		return true
+	})
+}
+
+/**
+ * 调用表达式的函数部分是否为类型, 用于区分类型转换和函数调用
+ */
+func (this *analysisTool) isTypeNameExpr(t ast.Expr) bool {
+
+	var ident *ast.Ident
+
+	switch expr := t.(type) {
+	case *ast.ParenExpr:
+		return this.isTypeNameExpr(expr.X)
+	case *ast.StarExpr:
+		return this.isTypeNameExpr(expr.X)
+	case *ast.IndexExpr, *ast.IndexListExpr:
+		return this.isTypeNameExpr(genericBaseType(expr))
+	case *ast.ArrayType, *ast.MapType, *ast.ChanType, *ast.FuncType:
+		return true
+	case *ast.Ident:
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return false
+	}
+
+	if this.typeChecker != nil {
+		if obj, found := this.typeChecker.info.Uses[ident]; found {
+			_, isTypeName := obj.(*types.TypeName)
+			return isTypeName
+		}
+	}
+
+	if selectorExpr, ok := t.(*ast.SelectorExpr); ok {
+		// 只处理 包名.类型名, 不处理 变量.方法名
+		alias, ok := selectorExpr.X.(*ast.Ident)
+		if !ok {
+			return false
+		}
+		for _, importMeta := range this.currentFileImports {
+			if importMeta.Alias == alias.Name {
+				packagePath := this.findPackagePathByAlias(alias.Name, ident.Name)
+				return this.existStructOrInterfaceInPackage(ident.Name, packagePath) ||
+					this.existTypeAliasInPackage(ident.Name, packagePath)
+			}
+		}
+		return false
+	}
+
+	return this.existStructOrInterfaceInPackage(ident.Name, this.currentPackagePath) ||
+		this.existTypeAliasInPackage(ident.Name, this.currentPackagePath)
+}
+
+func (this *analysisTool) appendUses(uses []*typeUse, method string, t ast.Expr, refs ...typeMeta) []*typeUse {
+	for _, ref := range refs {
+		found := false
+		for _, use := range uses {
+			found = found || use.target == ref
+		}
+		if !found {
+			uses = append(uses, &typeUse{
+				target:   ref,
+				method:   method,
+				position: this.currentFset.Position(t.Pos()),
+			})
+		}
+	}
+	return uses
+}
+
+// 两个类型之间的关系, 用于判断是否已经有关系
+type relationKey struct {
+	source typeMeta
+	target typeMeta
+}
+
+/**
+ * 所有文件解析完成后, 把方法中依赖的类型画成 ..> , 已经有字段或嵌入关系的不再重复画
+ */
+func (this *analysisTool) visitUses() {
+
+	existing := map[relationKey]bool{}
+	for _, d := range this.dependencyRelations {
+		existing[relationKey{d.source, d.target}] = true
+	}
+
+	for _, structMeta1 := range this.structMetas {
+		this.addUsesRelations(structMeta1, structMeta1.Uses, existing)
+	}
+
+	for _, interfaceMeta1 := range this.interfaceMetas {
+		this.addUsesRelations(interfaceMeta1, interfaceMeta1.Uses, existing)
+	}
+}
+
+func (this *analysisTool) addUsesRelations(source typeMeta, uses []*typeUse, existing map[relationKey]bool) {
+
+	for _, use := range uses {
+
+		key := relationKey{source, use.target}
+		if use.target == source || existing[key] {
+			continue
+		}
+		existing[key] = true
+
+		d := DependencyRelation{
+			source:   source,
+			target:   use.target,
+			kind:     relationDependency,
+			position: use.position,
+			method:   use.method,
+		}
+
+		this.dependencyRelations = append(this.dependencyRelations, &d)
+	}
+}
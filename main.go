@@ -5,6 +5,7 @@ import (
 	log "github.com/Sirupsen/logrus"
 	"github.com/jessevdk/go-flags"
 	"github.com/maobuji/go-package-plantuml/codeanalysis"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -16,74 +17,243 @@ func main() {
 	log.SetLevel(log.InfoLevel)
 
 	var opts struct {
-		CodeDir    string   `long:"codedir" description:"要扫描的代码目录" required:"true"`
-		GopathDir  string   `long:"gopath" description:"GOPATH目录"`
-		OutputFile string   `long:"outputfile" description:"解析结果保存到该文件中"`
-		IgnoreDirs []string `long:"ignoredir" description:"需要排除的目录,不需要扫描和解析"`
+		CodeDir         string   `long:"codedir" description:"要扫描的代码目录" required:"true"`
+		GopathDir       string   `long:"gopath" description:"GOPATH目录,代码目录在go.mod模块中时可以不设置"`
+		ModCache        string   `long:"modcache" description:"Go模块缓存目录,默认使用GOMODCACHE环境变量或GOPATH/pkg/mod"`
+		OutputFile      string   `long:"outputfile" description:"解析结果保存到该文件中"`
+		IgnoreDirs      []string `long:"ignoredir" description:"需要排除的目录,不需要扫描和解析"`
+		TypeCheck       bool     `long:"typecheck" description:"使用go/types进行类型检查,从本地源码确定类型所在的包,失败时使用语法树推断"`
+		ShowAlias       bool     `long:"showalias" description:"在UML中显示类型别名"`
+		ShowPromoted    bool     `long:"showpromoted" description:"在嵌入了其他类型的struct中列出提升的字段和方法"`
+		Detail          string   `long:"detail" description:"UML图的详细程度,full:全部类型和成员,public-api:只有导出的类型和成员,signatures:不显示字段,names-only:只显示类型名" choice:"full" choice:"public-api" choice:"signatures" choice:"names-only" default:"full"`
+		MaxMembers      int      `long:"maxmembers" description:"每个类最多显示的成员数量,超出的部分显示为... N more,0为不限制"`
+		Relations       string   `long:"relations" description:"关系的来源,用逗号分隔,fields:字段,signatures:方法参数和返回值,bodies:方法体中使用的类型" default:"fields,signatures"`
+		Format          string   `long:"format" description:"输出格式,plantuml:PlantUML类图,mermaid:Mermaid类图,dot:Graphviz DOT图,svg:不依赖Java和Graphviz直接生成SVG图" choice:"plantuml" choice:"mermaid" choice:"dot" choice:"svg" default:"plantuml"`
+		View            string   `long:"view" description:"图的内容,types:类型图,packages:包的import关系图" choice:"types" choice:"packages" default:"types"`
+		Collapse        string   `long:"collapse" description:"包的import关系图中合并为一个节点的包,用逗号分隔,stdlib:标准库,vendor:vendor目录中的包,external:外部模块"`
+		Cycles          bool     `long:"cycles" description:"查找包的import关系和类型之间的循环依赖,把报告输出到标准输出"`
+		HighlightCycles bool     `long:"highlightcycles" description:"在图中用红色画出循环依赖,需要同时使用--cycles"`
+		Rules           string   `long:"rules" description:"check命令使用的架构规则文件,JSON格式"`
+		Metrics         bool     `long:"metrics" description:"把每个包的耦合度和抽象度表格输出到标准输出"`
+		MetricsFile     string   `long:"metricsfile" description:"把每个包的耦合度和抽象度保存到该文件中,按照扩展名使用.csv或.json格式"`
+		Focus           string   `long:"focus" description:"只显示从该类型出发--depth步以内的类型,格式为包路径.类型名,例如github.com/a/b.User"`
+		Depth           int      `long:"depth" description:"--focus的步数,超出的类型只显示类型名" default:"1"`
+		Direction       string   `long:"direction" description:"--focus时沿着关系的方向,out:依赖的类型,in:依赖它的类型,both:两个方向" choice:"out" choice:"in" choice:"both" default:"both"`
+		IncludePkg      []string `long:"includepkg" description:"只显示匹配的包中的类型,*匹配一段,**匹配任意多段,例如internal/**"`
+		ExcludePkg      []string `long:"excludepkg" description:"不显示匹配的包中的类型"`
+		IncludeFile     []string `long:"includefile" description:"只解析匹配的go文件,没有/时匹配文件名,有/时匹配相对于代码目录的路径"`
+		ExcludeFile     []string `long:"excludefile" description:"不解析匹配的go文件,例如*_gen.go,mock_*.go"`
+		IncludeType     []string `long:"includetype" description:"只显示类型名匹配正则表达式的类型"`
+		ExcludeType     []string `long:"excludetype" description:"不显示类型名匹配正则表达式的类型"`
+		Gitignore       bool     `long:"gitignore" description:"不解析.gitignore中忽略的文件"`
+		ShowExcluded    bool     `long:"showexcluded" description:"排除的类型被图中的类型使用时显示为灰色的边界类型"`
+		Generated       string   `long:"generated" description:"有Code generated ... DO NOT EDIT.注释的生成代码的处理方式,show:和手写的代码一样显示,skip:不解析,separate:加上<<generated>>单独分组,collapse:每个包中生成的类型合并为一个节点" choice:"show" choice:"skip" choice:"separate" choice:"collapse" default:"show"`
+		GOOS            string   `long:"goos" description:"按照构建约束选择go文件时的操作系统,例如windows,默认为当前环境"`
+		GOARCH          string   `long:"goarch" description:"按照构建约束选择go文件时的CPU架构,例如arm64,默认为当前环境"`
+		Tags            string   `long:"tags" description:"构建标签,多个用逗号分隔,例如integration,debug"`
+		ShowPlatform    bool     `long:"showplatform" description:"把类型所在文件的构建约束显示为构造型,例如<<linux>>"`
+		Tests           bool     `long:"tests" description:"解析_test.go文件,测试中的类型和外部测试包单独分组,画出测试文件到测试函数使用的类型的关系"`
 	}
 
 	if len(os.Args) == 1 {
 		fmt.Println("使用例子\n" +
-			os.Args[0] + " --codedir /appdev/gopath/src/github.com/contiv/netplugin --gopath /appdev/gopath --outputfile  /tmp/result")
+			os.Args[0] + " --codedir /appdev/gopath/src/github.com/contiv/netplugin --gopath /appdev/gopath --outputfile  /tmp/result\n" +
+			"检查架构规则\n" +
+			os.Args[0] + " check --codedir /appdev/gopath/src/github.com/contiv/netplugin --gopath /appdev/gopath --rules /appdev/rules.json")
 		os.Exit(1)
 	}
 
-	_, err := flags.ParseArgs(&opts, os.Args)
+	args, err := flags.ParseArgs(&opts, os.Args)
 
 	if err != nil {
 		os.Exit(1)
 	}
 
+	// 第一个参数是程序名, 只支持check命令
+	check := len(args) > 1 && args[1] == "check"
+	if len(args) > 2 || (len(args) == 2 && !check) {
+		fmt.Printf("不支持的命令%s, 只支持check\n", strings.Join(args[1:], " "))
+		os.Exit(1)
+	}
+
 	if opts.CodeDir == "" {
 		panic("代码目录不能为空")
 		os.Exit(1)
 	}
 
+	opts.CodeDir, _ = filepath.Abs(opts.CodeDir)
+
 	if opts.GopathDir == "" {
 		opts.GopathDir = os.Getenv("GOPATH")
-		if opts.GopathDir == "" {
+	}
+
+	// check命令不生成图
+	if !check {
+		if opts.OutputFile == "" {
+			fmt.Println("输出文件未设置使用puml.txt做为输出文件")
+			opts.OutputFile = "puml.txt"
+		}
+		opts.OutputFile, _ = filepath.Abs(opts.OutputFile)
+
+		currentPath := getCurrentDirectory(opts.OutputFile)
+		createErr := os.MkdirAll(currentPath, 0777)
+		if err != nil {
+			fmt.Printf("%s", createErr)
 			panic("GOPATH目录不能为空")
 			os.Exit(1)
 		}
 	}
 
-	if opts.OutputFile == "" {
-		fmt.Println("输出文件未设置使用puml.txt做为输出文件")
-		opts.OutputFile = "puml.txt"
+	// 不在代码目录中的目录不会被扫描, 不需要检查
+	for index, dir := range opts.IgnoreDirs {
+		opts.IgnoreDirs[index], _ = filepath.Abs(dir)
 	}
-	opts.OutputFile, _ = filepath.Abs(opts.OutputFile)
 
-	currentPath := getCurrentDirectory(opts.OutputFile)
-	createErr := os.MkdirAll(currentPath, 0777)
-	if err != nil {
-		fmt.Printf("%s", createErr)
-		panic("GOPATH目录不能为空")
-		os.Exit(1)
+	config := codeanalysis.Config{
+		CodeDir:           opts.CodeDir,
+		GopathDir:         opts.GopathDir,
+		VendorDir:         path.Join(opts.CodeDir, "vendor"),
+		ModCacheDir:       opts.ModCache,
+		IgnoreDirs:        opts.IgnoreDirs,
+		TypeCheck:         opts.TypeCheck,
+		ShowTypeAliases:   opts.ShowAlias,
+		ShowPromoted:      opts.ShowPromoted,
+		Detail:            opts.Detail,
+		MaxMembers:        opts.MaxMembers,
+		RelationSources:   splitList(opts.Relations),
+		Format:            opts.Format,
+		View:              opts.View,
+		CollapsePackages:  splitList(opts.Collapse),
+		DetectCycles:      opts.Cycles,
+		HighlightCycles:   opts.Cycles && opts.HighlightCycles,
+		Focus:             opts.Focus,
+		FocusDepth:        opts.Depth,
+		FocusDirection:    opts.Direction,
+		IncludePackages:   opts.IncludePkg,
+		ExcludePackages:   opts.ExcludePkg,
+		IncludeFiles:      opts.IncludeFile,
+		ExcludeFiles:      opts.ExcludeFile,
+		IncludeTypes:      opts.IncludeType,
+		ExcludeTypes:      opts.ExcludeType,
+		UseGitignore:      opts.Gitignore,
+		ShowExcludedStubs: opts.ShowExcluded,
+		Generated:         opts.Generated,
+		GOOS:              opts.GOOS,
+		GOARCH:            opts.GOARCH,
+		BuildTags:         splitList(opts.Tags),
+		ShowPlatform:      opts.ShowPlatform,
+		Tests:             opts.Tests,
+	}
+
+	if check {
+		os.Exit(checkRules(config, opts.Rules))
 	}
 
-	if !strings.HasPrefix(opts.CodeDir, opts.GopathDir) {
-		panic(fmt.Sprintf("代码目录%s,必须是GOPATH目录%s的子目录", opts.CodeDir, opts.GopathDir))
+	result := codeanalysis.AnalysisCode(config)
+
+	if result.Err() != nil {
+		fmt.Println(result.Err())
 		os.Exit(1)
 	}
 
-	for _, dir := range opts.IgnoreDirs {
-		if !strings.HasPrefix(dir, opts.CodeDir) {
-			panic(fmt.Sprintf("需要排除的目录%s,必须是代码目录%s的子目录", dir, opts.CodeDir))
-			os.Exit(1)
-		}
+	result.OutputToFile(opts.OutputFile)
+
+	if opts.Cycles {
+		fmt.Print(result.CycleReport())
 	}
 
-	config := codeanalysis.Config{
-		CodeDir:    opts.CodeDir,
-		GopathDir:  opts.GopathDir,
-		VendorDir:  path.Join(opts.CodeDir, "vendor"),
-		IgnoreDirs: opts.IgnoreDirs,
+	if opts.Metrics {
+		fmt.Print(codeanalysis.MetricsTable(result.Metrics()))
 	}
 
+	if opts.MetricsFile != "" {
+		saveMetrics(opts.MetricsFile, result.Metrics())
+	}
+
+}
+
+/**
+ * 检查架构规则, 返回进程的退出码, 有违反规则的依赖时为1, 规则文件或代码目录错误时为2
+ */
+func checkRules(config codeanalysis.Config, rulesFile string) int {
+
+	if rulesFile == "" {
+		fmt.Println("check命令需要使用--rules设置规则文件")
+		return 2
+	}
+
+	if !codeanalysis.PathExists(config.CodeDir) {
+		fmt.Printf("找不到代码目录%s\n", config.CodeDir)
+		return 2
+	}
+
+	rules, err := codeanalysis.LoadRules(rulesFile)
+	if err != nil {
+		fmt.Println(err)
+		return 2
+	}
+
+	config.Rules = rules
+	// 合并后的包无法匹配规则中的包路径
+	config.CollapsePackages = nil
+	// interfacesOnly也要检查方法体中使用的具体类型
+	config.RelationSources = []string{codeanalysis.RelationFields, codeanalysis.RelationSignatures, codeanalysis.RelationBodies}
+
 	result := codeanalysis.AnalysisCode(config)
+	if result.Err() != nil {
+		fmt.Println(result.Err())
+		return 2
+	}
 
-	result.OutputToFile(opts.OutputFile)
+	violations := result.RuleViolations()
+
+	for _, violation := range violations {
+		fmt.Println(violation)
+	}
+
+	if len(violations) > 0 {
+		fmt.Printf("发现%d个违反架构规则的依赖\n", len(violations))
+		return 1
+	}
 
+	fmt.Println("没有违反架构规则的依赖")
+	return 0
+}
+
+/**
+ * 按照扩展名保存为CSV或JSON
+ */
+func saveMetrics(file string, metrics []*codeanalysis.PackageMetrics) {
+
+	content := ""
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		content = codeanalysis.MetricsCSV(metrics)
+	case ".json":
+		content = codeanalysis.MetricsJSON(metrics)
+	default:
+		fmt.Printf("不支持的度量文件格式%s, 扩展名只能为.csv或.json\n", file)
+		os.Exit(1)
+	}
+
+	if err := ioutil.WriteFile(file, []byte(content), 0666); err != nil {
+		fmt.Printf("保存度量文件%s失败, %s\n", file, err)
+		os.Exit(1)
+	}
+	log.Infof("度量已保存到%s\n", file)
+}
+
+/**
+ * 逗号分隔的列表, 去掉空格和空的项, 例如 "fields, signatures," 为 [fields signatures], 没有任何项时为nil
+ */
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
 }
 
 func getCurrentDirectory(tempFile string) string {
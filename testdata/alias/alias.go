@@ -0,0 +1,27 @@
+package alias
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/alias/model"
+)
+
+type User = model.User
+
+type Store = model.Store
+
+type LocalAlias = Local
+
+type PtrUser = *model.User
+
+type Local struct {
+}
+
+type Service struct {
+	user  User
+	users []User
+	local LocalAlias
+	ptr   PtrUser
+}
+
+type Saver interface {
+	Save(u User)
+}
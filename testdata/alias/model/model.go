@@ -0,0 +1,14 @@
+package model
+
+type User struct {
+}
+
+type Store interface {
+	Get()
+}
+
+type Repo struct {
+}
+
+func (this *Repo) Save(u User) {
+}
@@ -0,0 +1,13 @@
+package customer
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/cycle/order"
+)
+
+type Customer struct {
+	name string
+}
+
+func (this *Customer) Orders() []*order.Order {
+	return nil
+}
@@ -0,0 +1,18 @@
+package order
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/cycle/customer"
+)
+
+type Order struct {
+	buyer *customer.Customer
+	items []*Item
+}
+
+type Item struct {
+	order *Order
+}
+
+type Node struct {
+	next *Node
+}
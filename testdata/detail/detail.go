@@ -0,0 +1,23 @@
+package detail
+
+type Service struct {
+	Name  string
+	count int
+	cache map[string]string
+	Store store
+}
+
+func (this *Service) Start() error {
+	return nil
+}
+
+func (this *Service) stop() {
+}
+
+type store struct {
+}
+
+type Handler interface {
+	Handle()
+	reset()
+}
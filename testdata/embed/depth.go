@@ -0,0 +1,38 @@
+package embed
+
+type Inner struct {
+	X int
+	Y int
+}
+
+type Middle struct {
+	Inner
+}
+
+type Shallow struct {
+	X string
+}
+
+// Shallow.X在深度1, 覆盖了深度2的Inner.X
+type Outer struct {
+	Shallow
+	Middle
+}
+
+type Left struct {
+	Z int
+}
+
+type Right struct {
+	Z int
+}
+
+type Pair struct {
+	Left
+	Right
+}
+
+// Pair中的Left.Z和Right.Z在同一个深度, 不会提升
+type Wrapper struct {
+	Pair
+}
@@ -0,0 +1,47 @@
+package embed
+
+import (
+	"io"
+)
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
+
+type ReadWriter interface {
+	io.Reader
+	io.Writer
+}
+
+type Base struct {
+	ID int
+}
+
+func (this *Base) Save() error {
+	return nil
+}
+
+type Named struct {
+	Name string
+}
+
+func (this Named) Save() error {
+	return nil
+}
+
+type Entity struct {
+	Base
+	*Named
+	Reader
+	Title string
+}
+
+type Record struct {
+	*Base
+	ID string
+}
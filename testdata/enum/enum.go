@@ -0,0 +1,31 @@
+package enum
+
+type State int
+
+const (
+	StateIdle State = iota
+	StateRunning
+	_
+	StateStopped
+)
+
+type Color string
+
+const (
+	Red   Color = "red"
+	Green Color = "green"
+)
+
+const Untyped = 1
+
+type Level int
+
+const (
+	Low = Level(iota)
+	High
+)
+
+type Machine struct {
+	state  State
+	colors []Color
+}
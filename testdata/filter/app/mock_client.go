@@ -0,0 +1,9 @@
+package app
+
+type MockClient struct {
+	calls int
+}
+
+func (this *MockClient) Do() error {
+	return nil
+}
@@ -0,0 +1,5 @@
+package app
+
+type GeneratedModel struct {
+	id int
+}
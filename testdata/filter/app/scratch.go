@@ -0,0 +1,5 @@
+package app
+
+type Scratch struct {
+	note string
+}
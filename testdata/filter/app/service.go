@@ -0,0 +1,18 @@
+package app
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/filter/store"
+)
+
+type Service struct {
+	repo   *store.Repo
+	client Client
+}
+
+type Client interface {
+	Do() error
+}
+
+type internalHelper struct {
+	service *Service
+}
@@ -0,0 +1,5 @@
+package build
+
+type Artifact struct {
+	path string
+}
@@ -0,0 +1,5 @@
+package store
+
+type Repo struct {
+	dsn string
+}
@@ -0,0 +1,29 @@
+package focus
+
+type Caller struct {
+	root *Root
+}
+
+type Root struct {
+	next *Middle
+}
+
+type Middle struct {
+	leaf Leaf
+}
+
+func (this *Middle) Name() string {
+	return ""
+}
+
+type Leaf struct {
+	end *End
+}
+
+type End struct {
+	caller *Caller
+}
+
+type Namer interface {
+	Name() string
+}
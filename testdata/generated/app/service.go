@@ -0,0 +1,15 @@
+package app
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/generated/pb"
+)
+
+type Service struct {
+	client pb.UserServiceClient
+	user   *pb.User
+	store  Store
+}
+
+type Store interface {
+	Load() string
+}
@@ -0,0 +1,42 @@
+package generics
+
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+type User struct {
+}
+
+type Box[T any] struct {
+	value T
+}
+
+func (this *Box[T]) Get() T {
+	return this.value
+}
+
+type List[T any] struct {
+	items []Box[T]
+	next  *List[T]
+}
+
+func (this *List[T]) Push(v T) {
+}
+
+type Pair[K comparable, V Number] struct {
+	key   K
+	value V
+}
+
+func (this Pair[K, V]) Key() K {
+	return this.key
+}
+
+type Getter[T any] interface {
+	Get() T
+}
+
+type Registry struct {
+	users []Box[User]
+	pairs map[string]Pair[string, int]
+}
@@ -0,0 +1,32 @@
+package methods
+
+import (
+	"io"
+)
+
+type Option func(c *Client)
+
+type Client struct {
+	name string
+}
+
+func (this *Client) Do(method string, url string, opts ...Option) (int, error) {
+	return 0, nil
+}
+
+func (this Client) Name() string {
+	return this.name
+}
+
+func (this *Client) read(r io.Reader, a, b int) (n int, err error) {
+	return 0, nil
+}
+
+type Reader interface {
+	Read(p []byte) (n int, err error)
+}
+
+type ReadCloser interface {
+	Reader
+	Close() error
+}
@@ -0,0 +1,39 @@
+package methodset
+
+type Status int
+
+type Reader interface {
+	Read(p []byte) (int, error)
+}
+
+type Closer interface {
+	Close() error
+}
+
+type ReadCloser interface {
+	Reader
+	Closer
+}
+
+type StatusSetter interface {
+	SetStatus(s Status)
+}
+
+type File struct {
+}
+
+func (this File) Read(p []byte) (int, error) {
+	return 0, nil
+}
+
+func (this *File) Close() error {
+	return nil
+}
+
+type LogFile struct {
+	File
+}
+
+type PtrLogFile struct {
+	*File
+}
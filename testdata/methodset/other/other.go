@@ -0,0 +1,9 @@
+package other
+
+type Status int
+
+type Machine struct {
+}
+
+func (this *Machine) SetStatus(s Status) {
+}
@@ -0,0 +1,10 @@
+module example.com/app
+
+go 1.21
+
+require (
+	example.com/Upper v1.0.0
+	example.com/dep v0.0.0
+)
+
+replace example.com/dep => ../dep
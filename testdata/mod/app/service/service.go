@@ -0,0 +1,10 @@
+package service
+
+import (
+	"example.com/dep/store"
+	"example.com/Upper"
+)
+
+type Repo interface {
+	Save(item storage.Item) upper.U
+}
@@ -0,0 +1,4 @@
+package storage
+
+type Item struct {
+}
@@ -0,0 +1,4 @@
+package upper
+
+type U struct {
+}
@@ -0,0 +1,38 @@
+package named
+
+import "net/http"
+
+type Status int
+
+func (this Status) String() string {
+	return ""
+}
+
+type Handler interface {
+	Handle()
+}
+
+type HandlerFunc func()
+
+func (this HandlerFunc) Handle() {
+	this()
+}
+
+type Handlers []HandlerFunc
+
+func (this Handlers) Handle() {
+}
+
+type Middleware func(http.Handler) http.Handler
+
+func (this Middleware) Wrap(h http.Handler) http.Handler {
+	return this(h)
+}
+
+type Plain string
+
+type Server struct {
+	status   Status
+	handlers Handlers
+	plain    Plain
+}
@@ -0,0 +1,19 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ext/lib"
+	"github.com/maobuji/go-package-plantuml/testdata/packages/model"
+	_ "example.com/unknown/driver"
+)
+
+type App struct {
+	user *model.User
+	opts lib.Options
+}
+
+func (this *App) Name() string {
+	return strings.ToUpper(fmt.Sprint(this.user))
+}
@@ -0,0 +1,11 @@
+package app
+
+import (
+	"fmt"
+
+	"github.com/maobuji/go-package-plantuml/testdata/packages/model"
+)
+
+func Run(user *model.User) {
+	fmt.Println(user)
+}
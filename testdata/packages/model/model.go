@@ -0,0 +1,9 @@
+package model
+
+import (
+	"time"
+)
+
+type User struct {
+	Created time.Time
+}
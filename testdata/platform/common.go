@@ -0,0 +1,6 @@
+package platform
+
+type Server struct {
+	conn   *Conn
+	poller *Poller
+}
@@ -0,0 +1,11 @@
+package platform
+
+import "testing"
+
+type fakeConn struct {
+	conn *Conn
+}
+
+func TestConn(t *testing.T) {
+	_ = fakeConn{conn: &Conn{}}
+}
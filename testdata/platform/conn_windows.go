@@ -0,0 +1,5 @@
+package platform
+
+type Conn struct {
+	handle uintptr
+}
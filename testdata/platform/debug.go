@@ -0,0 +1,7 @@
+//go:build debug
+
+package platform
+
+type Tracer struct {
+	server *Server
+}
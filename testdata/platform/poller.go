@@ -0,0 +1,7 @@
+//go:build linux || darwin
+
+package platform
+
+type Poller struct {
+	epfd int
+}
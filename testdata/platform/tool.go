@@ -0,0 +1,6 @@
+//go:build ignore
+
+package main
+
+type Generator struct {
+}
@@ -0,0 +1,5 @@
+package log
+
+type Logger interface {
+	Log(message string)
+}
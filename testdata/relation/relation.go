@@ -0,0 +1,26 @@
+package relation
+
+import (
+	"io"
+
+	"github.com/maobuji/go-package-plantuml/testdata/relation/log"
+)
+
+type Storage interface {
+	Get(key string) string
+}
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+)
+
+type Service struct {
+	store   Storage
+	loggers []log.Logger
+	level   Level
+	byName  map[string]Storage
+	reader  io.Reader
+}
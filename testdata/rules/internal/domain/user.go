@@ -0,0 +1,9 @@
+package domain
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/rules/internal/infra/db"
+)
+
+type User struct {
+	conn *db.Conn
+}
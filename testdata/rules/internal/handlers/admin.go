@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/rules/internal/services"
+)
+
+type Admin struct {
+	name string
+}
+
+func (this *Admin) Reset() {
+	service := services.UserService{}
+	_ = service
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/rules/internal/services"
+)
+
+func NewHandler(users *services.UserService, store services.Store) *Handler {
+	return &Handler{store: store, users: users}
+}
+
+func resetUsers() {
+	users := services.UserService{}
+	_ = users
+}
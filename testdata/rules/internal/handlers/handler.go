@@ -0,0 +1,18 @@
+package handlers
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/rules/internal/services"
+)
+
+type Handler struct {
+	store services.Store
+	users *services.UserService
+}
+
+func (this *Handler) Service() *services.UserService {
+	return this.users
+}
+
+func (this *Handler) Check(s services.Store) bool {
+	return s != nil
+}
@@ -0,0 +1,9 @@
+package services
+
+type UserService struct {
+	name string
+}
+
+type Store interface {
+	Save(key string) error
+}
@@ -0,0 +1,23 @@
+package shape
+
+type Request struct {
+}
+
+type Response struct {
+}
+
+type Event struct {
+}
+
+type Order struct {
+	request   Request
+	response  *Response
+	events    []*Event
+	last      [3]Event
+	byID      map[int64]*Event
+	queue     chan Event
+	notify    <-chan *Event
+	handler   func(*Request) (*Response, error)
+	batches   [][]Event
+	responses *[]Response
+}
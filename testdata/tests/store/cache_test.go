@@ -0,0 +1,31 @@
+package store
+
+import "testing"
+
+type fakeStore struct {
+	values map[string]string
+}
+
+func (this *fakeStore) Get(key string) (string, error) {
+	return this.values[key], nil
+}
+
+// 测试文件中给非测试类型定义的方法
+func (this *Latest) bump() {
+	this.version++
+}
+
+func TestCacheGet(t *testing.T) {
+	cache := NewCache(&fakeStore{})
+	cache.Get("a")
+}
+
+func TestLatest(t *testing.T) {
+	latest := Latest{}
+	latest.bump()
+	// 字段名和类型名相同, 不是使用了Store
+	_ = latest.Store
+}
+
+func testHelper() {
+}
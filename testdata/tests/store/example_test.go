@@ -0,0 +1,16 @@
+package store_test
+
+import (
+	"fmt"
+
+	"github.com/maobuji/go-package-plantuml/testdata/tests/store"
+)
+
+type memoryStore struct {
+	cache *store.Cache
+}
+
+func ExampleCache() {
+	var s store.Store
+	fmt.Println(store.NewCache(s))
+}
@@ -0,0 +1,7 @@
+package store
+
+// 文件名以test.go结尾, 但不是测试文件
+type Latest struct {
+	version int
+	Store   string
+}
@@ -0,0 +1,18 @@
+package store
+
+type Store interface {
+	Get(key string) (string, error)
+}
+
+type Cache struct {
+	store Store
+	items map[string]string
+}
+
+func NewCache(store Store) *Cache {
+	return &Cache{store: store, items: map[string]string{}}
+}
+
+func (this *Cache) Get(key string) (string, error) {
+	return this.store.Get(key)
+}
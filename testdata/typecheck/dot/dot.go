@@ -0,0 +1,4 @@
+package dot
+
+type Dot struct {
+}
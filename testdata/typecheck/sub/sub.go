@@ -0,0 +1,4 @@
+package sub
+
+type Sub struct {
+}
@@ -0,0 +1,15 @@
+package typecheck
+
+import (
+	. "github.com/maobuji/go-package-plantuml/testdata/typecheck/dot"
+	other "github.com/maobuji/go-package-plantuml/testdata/typecheck/sub"
+)
+
+type Holder struct {
+	d Dot
+	o other.Sub
+	l Local
+}
+
+type Local struct {
+}
@@ -0,0 +1,7 @@
+package model
+
+type Option struct {
+}
+
+type Item struct {
+}
@@ -0,0 +1,46 @@
+package uses
+
+import (
+	"github.com/maobuji/go-package-plantuml/testdata/uses/model"
+)
+
+type Request struct {
+}
+
+type Order struct {
+}
+
+type Cache struct {
+}
+
+type Status int
+
+func (this Status) String() string {
+	return ""
+}
+
+type Logger interface {
+	Log(r *Request)
+}
+
+type Handler struct {
+	logger Logger
+}
+
+func (this *Handler) Handle(r *Request, opts ...func(*model.Option)) (*Order, error) {
+	var cache Cache
+	_ = cache
+
+	status := Status(1)
+	_ = status
+
+	item := &model.Item{}
+	_ = item
+
+	this.logger.Log(r)
+
+	orders := []Order{}
+	_ = orders
+
+	return nil, nil
+}